@@ -17,7 +17,7 @@ func Eq(val interface{}) P {
 		if reflect.DeepEqual(x, val) {
 			return x, nil
 		}
-		return nil, state.Trap("Expact %v but %v", val, x)
+		return nil, Raise(state, "eq", val, x)
 	}
 }
 
@@ -29,7 +29,7 @@ func Ne(val interface{}) P {
 			return nil, err
 		}
 		if reflect.DeepEqual(x, val) {
-			return nil, state.Trap("Expact not %v but %v", val, x)
+			return nil, Raise(state, "ne", val, x)
 		}
 		return x, nil
 	}
@@ -53,7 +53,7 @@ func Fail(message string, args ...interface{}) P {
 func EOF(state State) (interface{}, error) {
 	data, err := state.Next()
 	if err == nil {
-		return nil, state.Trap("Expect eof but %v", data)
+		return nil, Raise(state, "eof.expected", data)
 	}
 	return nil, nil
 }
@@ -70,7 +70,7 @@ func OneOf(args ...interface{}) P {
 				return data, nil
 			}
 		}
-		return nil, state.Trap("Expect one of [%v] but %v", args, data)
+		return nil, Raise(state, "one-of", args, data)
 	}
 }
 
@@ -83,7 +83,7 @@ func NoneOf(args ...interface{}) P {
 		}
 		for element := range args {
 			if reflect.DeepEqual(data, element) {
-				return nil, state.Trap("Expect none of [%v] but %v", args, data)
+				return nil, Raise(state, "none-of", args, data)
 			}
 		}
 		return data, nil
@@ -0,0 +1,144 @@
+package goP2
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// BasicStateFromBytes 构造一个以字节为元素的 BasicState ，用于解析二进制数据
+func BasicStateFromBytes(data []byte) BasicState {
+	buffer := make([]interface{}, 0, len(data))
+	for _, b := range data {
+		buffer = append(buffer, b)
+	}
+	return BasicState{
+		buffer,
+		0,
+		-1,
+	}
+}
+
+// takeChunk 是 Take 预先分配的最大容量，避免伪造的长度字段一次申请过多的内存
+const takeChunk = 4096
+
+// Take 读取后续的 n 个字节，返回 []byte
+func Take(n int) P {
+	return func(state State) (interface{}, error) {
+		size := n
+		if size > takeChunk {
+			size = takeChunk
+		}
+		re := make([]byte, 0, size)
+		for i := 0; i < n; i++ {
+			x, err := state.Next()
+			if err != nil {
+				return nil, err
+			}
+			b, ok := x.(byte)
+			if !ok {
+				return nil, Raise(state, "element-type", "byte", x)
+			}
+			re = append(re, b)
+		}
+		return re, nil
+	}
+}
+
+// fixedWidth 读取 n 个字节并用 decode 转换
+func fixedWidth(n int, decode func([]byte) interface{}) P {
+	take := Take(n)
+	return func(state State) (interface{}, error) {
+		data, err := take(state)
+		if err != nil {
+			return nil, err
+		}
+		return decode(data.([]byte)), nil
+	}
+}
+
+// Uint8 读取一个字节，返回 uint8
+var Uint8 = fixedWidth(1, func(b []byte) interface{} { return b[0] })
+
+// Int8 读取一个字节，返回 int8
+var Int8 = fixedWidth(1, func(b []byte) interface{} { return int8(b[0]) })
+
+// Uint16BE 读取大端序的 uint16
+var Uint16BE = fixedWidth(2, func(b []byte) interface{} { return binary.BigEndian.Uint16(b) })
+
+// Uint16LE 读取小端序的 uint16
+var Uint16LE = fixedWidth(2, func(b []byte) interface{} { return binary.LittleEndian.Uint16(b) })
+
+// Uint32BE 读取大端序的 uint32
+var Uint32BE = fixedWidth(4, func(b []byte) interface{} { return binary.BigEndian.Uint32(b) })
+
+// Uint32LE 读取小端序的 uint32
+var Uint32LE = fixedWidth(4, func(b []byte) interface{} { return binary.LittleEndian.Uint32(b) })
+
+// Uint64BE 读取大端序的 uint64
+var Uint64BE = fixedWidth(8, func(b []byte) interface{} { return binary.BigEndian.Uint64(b) })
+
+// Uint64LE 读取小端序的 uint64
+var Uint64LE = fixedWidth(8, func(b []byte) interface{} { return binary.LittleEndian.Uint64(b) })
+
+// Int16BE 读取大端序补码表示的 int16
+var Int16BE = fixedWidth(2, func(b []byte) interface{} { return int16(binary.BigEndian.Uint16(b)) })
+
+// Int16LE 读取小端序补码表示的 int16
+var Int16LE = fixedWidth(2, func(b []byte) interface{} { return int16(binary.LittleEndian.Uint16(b)) })
+
+// Int32BE 读取大端序补码表示的 int32
+var Int32BE = fixedWidth(4, func(b []byte) interface{} { return int32(binary.BigEndian.Uint32(b)) })
+
+// Int32LE 读取小端序补码表示的 int32
+var Int32LE = fixedWidth(4, func(b []byte) interface{} { return int32(binary.LittleEndian.Uint32(b)) })
+
+// Int64BE 读取大端序补码表示的 int64
+var Int64BE = fixedWidth(8, func(b []byte) interface{} { return int64(binary.BigEndian.Uint64(b)) })
+
+// Int64LE 读取小端序补码表示的 int64
+var Int64LE = fixedWidth(8, func(b []byte) interface{} { return int64(binary.LittleEndian.Uint64(b)) })
+
+// Float32BE 读取大端序的 IEEE 754 单精度浮点数，返回 float32
+var Float32BE = fixedWidth(4, func(b []byte) interface{} { return math.Float32frombits(binary.BigEndian.Uint32(b)) })
+
+// Float32LE 读取小端序的 IEEE 754 单精度浮点数，返回 float32
+var Float32LE = fixedWidth(4, func(b []byte) interface{} { return math.Float32frombits(binary.LittleEndian.Uint32(b)) })
+
+// Float64BE 读取大端序的 IEEE 754 双精度浮点数，返回 float64
+var Float64BE = fixedWidth(8, func(b []byte) interface{} { return math.Float64frombits(binary.BigEndian.Uint64(b)) })
+
+// Float64LE 读取小端序的 IEEE 754 双精度浮点数，返回 float64
+var Float64LE = fixedWidth(8, func(b []byte) interface{} { return math.Float64frombits(binary.LittleEndian.Uint64(b)) })
+
+// Uvarint 读取 LEB128 编码的无符号变长整数，返回 uint64 。超过 64 位时报错
+func Uvarint(state State) (interface{}, error) {
+	var re uint64
+	for shift := uint(0); ; shift += 7 {
+		x, err := state.Next()
+		if err != nil {
+			return nil, err
+		}
+		b, ok := x.(byte)
+		if !ok {
+			return nil, Raise(state, "element-type", "byte", x)
+		}
+		// 第十个字节只能贡献最高的一位
+		if shift == 63 && b > 1 {
+			return nil, Raise(state, "varint.overflow")
+		}
+		re |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return re, nil
+		}
+	}
+}
+
+// Varint 读取 zigzag 编码的有符号 LEB128 变长整数，返回 int64
+func Varint(state State) (interface{}, error) {
+	x, err := Uvarint(state)
+	if err != nil {
+		return nil, err
+	}
+	u := x.(uint64)
+	return int64(u>>1) ^ -int64(u&1), nil
+}
@@ -0,0 +1,102 @@
+package goP2
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFixedWidth(t *testing.T) {
+	data := []byte{
+		0xff,
+		0x01, 0x02,
+		0x01, 0x02, 0x03, 0x04,
+		0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x40, 0x49, 0x0f, 0xdb,
+		0x18, 0x2d, 0x44, 0x54, 0xfb, 0x21, 0x09, 0x40,
+	}
+	state := BasicStateFromBytes(data)
+	for _, c := range []struct {
+		p      P
+		expect interface{}
+	}{
+		{Int8, int8(-1)},
+		{Uint16BE, uint16(0x0102)},
+		{Uint32LE, uint32(0x04030201)},
+		{Int64LE, int64(-2)},
+		{Float32BE, float32(math.Pi)},
+		{Float64LE, math.Pi},
+	} {
+		re, err := c.p.Parse(&state)
+		if err != nil {
+			t.Fatalf("Expect %v but error %v", c.expect, err)
+		}
+		if re != c.expect {
+			t.Fatalf("Expect %v (%T) but %v (%T)", c.expect, c.expect, re, re)
+		}
+	}
+	if _, err := EOF(&state); err != nil {
+		t.Fatalf("Expect eof but %v", err)
+	}
+}
+
+func TestTake(t *testing.T) {
+	state := BasicStateFromBytes([]byte("\x03abcde"))
+	re, err := Uint8.Bind(func(n interface{}) P {
+		return Take(int(n.(uint8)))
+	}).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(re, []byte("abc")) {
+		t.Fatalf("Expect abc but %v", re)
+	}
+	if _, err := Take(3).Parse(&state); err == nil {
+		t.Fatalf("Expect eof error")
+	}
+	// 伪造的长度不会导致一次申请过多的内存
+	short := BasicStateFromBytes([]byte("abc"))
+	if _, err := Take(1 << 40).Parse(&short); err == nil {
+		t.Fatalf("Expect eof error for a forged length")
+	}
+	text := BasicStateFromText("ab")
+	if _, err := Take(1).Parse(&text); err == nil {
+		t.Fatalf("Expect error for rune state")
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for _, c := range []struct {
+		data   []byte
+		expect uint64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7f}, 127},
+		{[]byte{0xe5, 0x8e, 0x26}, 624485},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, math.MaxUint64},
+	} {
+		state := BasicStateFromBytes(c.data)
+		re, err := P(Uvarint).Over(EOF).Parse(&state)
+		if err != nil || re != c.expect {
+			t.Errorf("%x: Expect %d but %v, %v", c.data, c.expect, re, err)
+		}
+	}
+	for _, data := range [][]byte{
+		{0x80},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02},
+	} {
+		state := BasicStateFromBytes(data)
+		if re, err := Uvarint(&state); err == nil {
+			t.Errorf("%x: Expect error but %v", data, re)
+		}
+	}
+	state := BasicStateFromBytes([]byte{0x00, 0x01, 0x02, 0x03, 0xfe, 0xff, 0xff, 0xff, 0x0f})
+	re, err := Many(Varint).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []interface{}{int64(0), int64(-1), int64(1), int64(-2), int64(math.MaxInt32)}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %v but %v", expect, re)
+	}
+}
@@ -11,9 +11,9 @@ func Byte(val byte) P {
 			if c == val {
 				return c, nil
 			}
-			return nil, state.Trap("Expect '%v' but '%v'", string([]byte{val}), string([]byte{c}))
+			return nil, Raise(state, "chr", string([]byte{val}), string([]byte{c}))
 		}
-		return nil, state.Trap("Expect a byte '%s' but x is %v", string([]byte{val}), x)
+		return nil, Raise(state, "element-type", "byte", x)
 	}
 }
 
@@ -26,11 +26,11 @@ func NByte(val byte) P {
 		}
 		if c, ok := x.(byte); ok {
 			if c == val {
-				return nil, state.Trap("Expect not '%v' but '%v'", string([]byte{val}), string([]byte{c}))
+				return nil, Raise(state, "not-chr", string([]byte{val}), string([]byte{c}))
 			}
 			return c, nil
 		}
-		return nil, state.Trap("Expect a rune '%s' but x is %v", string([]byte{val}), x)
+		return nil, Raise(state, "element-type", "byte", x)
 	}
 }
 
@@ -48,9 +48,9 @@ func ByteOf(str string) P {
 					return c, nil
 				}
 			}
-			return nil, state.Trap("Expect rune in '%s' but '%s'", str, string([]byte{c}))
+			return nil, Raise(state, "rune-of", str, string([]byte{c}))
 		}
-		return nil, state.Trap("Expect rune in '%s' but x=%v is %t", str, x, x)
+		return nil, Raise(state, "element-type", "byte", x)
 	}
 }
 
@@ -65,12 +65,12 @@ func ByteNone(str string) P {
 		if c, ok := x.(byte); ok {
 			for _, r := range data {
 				if c == r {
-					return nil, state.Trap("Expect rune none of '%s' but '%s'", str, string([]byte{c}))
+					return nil, Raise(state, "rune-none-of", str, string([]byte{c}))
 				}
 			}
 			return c, nil
 		}
-		return nil, state.Trap("Expect rune none of '%s' but x=%v is %t", str, x, x)
+		return nil, Raise(state, "element-type", "byte", x)
 	}
 }
 
@@ -100,8 +100,8 @@ func ByteP(name string, pred func(r byte) bool) P {
 			if pred(r) {
 				return c, nil
 			}
-			return nil, state.Trap("Expect %s but '%v'", name, string([]byte{r}))
+			return nil, Raise(state, "rune-pred", name, string([]byte{r}))
 		}
-		return nil, state.Trap("Expect %s but x=%v is %t", name, x, x)
+		return nil, Raise(state, "element-type", "byte", x)
 	}
 }
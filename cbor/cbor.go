@@ -0,0 +1,343 @@
+// Package cbor 是基于 goP2 字节算子的 CBOR （RFC 8949）解码器，支持全部主类型，
+// 包括标签和不定长的字节串、文本串、数组与映射。
+//
+// 解码结果对应的 Go 类型：无符号整数为 uint64 ，负整数为 int64 （超出范围时为 *big.Int），
+// 字节串为 []byte ，文本串为 string ，数组为 []interface{} ，映射为 map[interface{}]interface{} ，
+// false/true 为 bool ， null 为 nil ， undefined 为 Undefined ，其它简单值为 Simple ，
+// 半精度和单精度浮点数为 float32 ，双精度浮点数为 float64 。
+// 标签 0 和 1 解码为 time.Time ，标签 2 和 3 解码为 *big.Int ，其它标签解码为 Tag 。
+package cbor
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+	"unicode/utf8"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/datetime"
+)
+
+// DefaultMaxDepth 是 Options.MaxDepth 为 0 时使用的嵌套深度上限
+const DefaultMaxDepth = 1000
+
+// Options 控制解码的行为
+type Options struct {
+	// MaxDepth 是数组、映射和标签嵌套深度的上限，为 0 时使用 DefaultMaxDepth
+	MaxDepth int
+}
+
+// Tag 是没有特别处理的标签
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// Simple 是没有预定义含义的简单值
+type Simple uint8
+
+// Undefined 是简单值 undefined
+type Undefined struct{}
+
+// ParseError 是解码错误， Offset 是字节偏移
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cbor: offset %d: %s", e.Offset, e.Message)
+}
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// Value 是解码一个 CBOR 数据项的算子
+var Value = Parser(Options{})
+
+// Parser 返回按照 opts 解码一个 CBOR 数据项的算子
+func Parser(opts Options) goP2.P {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	return goP2.Do(func(state goP2.State) interface{} {
+		d := &decoder{opts: opts}
+		return d.item(state)
+	})
+}
+
+// Decode 解码 data 中唯一的一个数据项，错误总是 *ParseError
+func Decode(data []byte, opts Options) (interface{}, error) {
+	state := goP2.BasicStateFromBytes(data)
+	re, err := Parser(opts).Over(end).Parse(&state)
+	if err != nil {
+		if e, ok := err.(goP2.Error); ok {
+			return nil, &ParseError{e.Pos, e.Message}
+		}
+		return nil, &ParseError{state.Pos(), err.Error()}
+	}
+	return re, nil
+}
+
+// end 在输入结束时成功，否则在剩余的第一个字节处报错
+var end = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	if _, err := state.Next(); err == nil {
+		fail(state, pos, "unexpected trailing data")
+	}
+	return nil
+})
+
+type decoder struct {
+	opts  Options
+	depth int
+}
+
+func (d *decoder) enter(state goP2.State, pos int) {
+	d.depth++
+	if d.depth > d.opts.MaxDepth {
+		fail(state, pos, "exceeded max depth %d", d.opts.MaxDepth)
+	}
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// indefinite 是附加信息 31 ，表示不定长
+const indefinite = 31
+
+// head 读取数据项的首字节和参数，返回主类型、附加信息和参数
+func head(state goP2.State) (major byte, info byte, arg uint64) {
+	pos := state.Pos()
+	b := goP2.Uint8.Exec(state).(uint8)
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info == 24:
+		arg = uint64(goP2.Uint8.Exec(state).(uint8))
+	case info == 25:
+		arg = uint64(goP2.Uint16BE.Exec(state).(uint16))
+	case info == 26:
+		arg = uint64(goP2.Uint32BE.Exec(state).(uint32))
+	case info == 27:
+		arg = goP2.Uint64BE.Exec(state).(uint64)
+	case info == indefinite:
+		if major < 2 || major == 6 {
+			fail(state, pos, "major type %d cannot be indefinite", major)
+		}
+	default:
+		fail(state, pos, "reserved additional information %d", info)
+	}
+	return major, info, arg
+}
+
+// isBreak 判断下一个字节是否是 break ，是则消费它
+func isBreak(state goP2.State) bool {
+	_, err := goP2.Try(goP2.Byte(0xff)).Parse(state)
+	return err == nil
+}
+
+// length 将参数转换为长度
+func length(state goP2.State, pos int, arg uint64) int {
+	if arg > math.MaxInt32 {
+		fail(state, pos, "length %d is too large", arg)
+	}
+	return int(arg)
+}
+
+func (d *decoder) item(state goP2.State) interface{} {
+	pos := state.Pos()
+	major, info, arg := head(state)
+	switch major {
+	case 0:
+		return arg
+	case 1:
+		if arg > math.MaxInt64 {
+			return new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(arg))
+		}
+		return -1 - int64(arg)
+	case 2:
+		return d.chunks(state, pos, major, info, arg)
+	case 3:
+		b := d.chunks(state, pos, major, info, arg)
+		if !utf8.Valid(b) {
+			fail(state, pos, "text string is not valid UTF-8")
+		}
+		return string(b)
+	case 4:
+		return d.array(state, pos, info, arg)
+	case 5:
+		return d.mapping(state, pos, info, arg)
+	case 6:
+		return d.tag(state, pos, arg)
+	}
+	return simple(state, pos, info, arg)
+}
+
+// chunks 读取字节串或者文本串，不定长时由同一主类型的定长分块拼接而成
+func (d *decoder) chunks(state goP2.State, pos int, major, info byte, arg uint64) []byte {
+	if info != indefinite {
+		return goP2.Take(length(state, pos, arg)).Exec(state).([]byte)
+	}
+	re := []byte{}
+	for !isBreak(state) {
+		chunk := state.Pos()
+		m, i, a := head(state)
+		if m != major || i == indefinite {
+			fail(state, chunk, "indefinite-length string chunk must be a definite string of the same type")
+		}
+		re = append(re, goP2.Take(length(state, chunk, a)).Exec(state).([]byte)...)
+	}
+	return re
+}
+
+func (d *decoder) array(state goP2.State, pos int, info byte, arg uint64) interface{} {
+	d.enter(state, pos)
+	defer d.leave()
+	// 不按照长度字段预先分配，伪造的长度会在读到结尾时失败
+	re := []interface{}{}
+	if info == indefinite {
+		for !isBreak(state) {
+			re = append(re, d.item(state))
+		}
+		return re
+	}
+	for n := length(state, pos, arg); len(re) < n; {
+		re = append(re, d.item(state))
+	}
+	return re
+}
+
+func (d *decoder) mapping(state goP2.State, pos int, info byte, arg uint64) interface{} {
+	d.enter(state, pos)
+	defer d.leave()
+	re := map[interface{}]interface{}{}
+	pair := func() {
+		keyPos := state.Pos()
+		key := d.item(state)
+		switch key.(type) {
+		case []byte, []interface{}, map[interface{}]interface{}, *big.Int, Tag:
+			fail(state, keyPos, "unhashable map key of type %T", key)
+		}
+		if _, ok := re[key]; ok {
+			fail(state, keyPos, "duplicate map key %v", key)
+		}
+		re[key] = d.item(state)
+	}
+	if info == indefinite {
+		for !isBreak(state) {
+			pair()
+		}
+		return re
+	}
+	for i, n := 0, length(state, pos, arg); i < n; i++ {
+		pair()
+	}
+	return re
+}
+
+func (d *decoder) tag(state goP2.State, pos int, number uint64) interface{} {
+	d.enter(state, pos)
+	defer d.leave()
+	content := d.item(state)
+	switch number {
+	case 0:
+		text, ok := content.(string)
+		if !ok {
+			fail(state, pos, "tag 0 expects a text string but %T", content)
+		}
+		sub := goP2.BasicStateFromText(text)
+		t, err := datetime.RFC3339.Over(goP2.EOF).Parse(&sub)
+		if err != nil {
+			fail(state, pos, "tag 0 expects an RFC 3339 date/time but %q", text)
+		}
+		return t
+	case 1:
+		switch v := content.(type) {
+		case uint64:
+			if v > math.MaxInt64 {
+				fail(state, pos, "tag 1 epoch %d is out of range", v)
+			}
+			return time.Unix(int64(v), 0).UTC()
+		case int64:
+			return time.Unix(v, 0).UTC()
+		case float32:
+			return epoch(state, pos, float64(v))
+		case float64:
+			return epoch(state, pos, v)
+		}
+		fail(state, pos, "tag 1 expects a number but %T", content)
+	case 2, 3:
+		b, ok := content.([]byte)
+		if !ok {
+			fail(state, pos, "tag %d expects a byte string but %T", number, content)
+		}
+		n := new(big.Int).SetBytes(b)
+		if number == 3 {
+			n.Sub(big.NewInt(-1), n)
+		}
+		return n
+	}
+	return Tag{number, content}
+}
+
+func epoch(state goP2.State, pos int, v float64) time.Time {
+	// NaN 、无穷大和超出 int64 的秒数转换为整数的结果没有定义
+	if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		fail(state, pos, "tag 1 epoch %v is out of range", v)
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// halfToFloat32 将 IEEE 754 半精度浮点数转换为 float32
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		v := float32(math.Ldexp(float64(mant), -24))
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
+
+// simple 解码主类型 7 的简单值和浮点数
+func simple(state goP2.State, pos int, info byte, arg uint64) interface{} {
+	switch info {
+	case 20:
+		return false
+	case 21:
+		return true
+	case 22:
+		return nil
+	case 23:
+		return Undefined{}
+	case 24:
+		if arg < 32 {
+			fail(state, pos, "simple value %d must use the short form", arg)
+		}
+		return Simple(arg)
+	case 25:
+		return halfToFloat32(uint16(arg))
+	case 26:
+		return math.Float32frombits(uint32(arg))
+	case 27:
+		return math.Float64frombits(arg)
+	case indefinite:
+		fail(state, pos, "unexpected break")
+	}
+	return Simple(arg)
+}
@@ -0,0 +1,153 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeHex(t *testing.T, text string) (interface{}, error) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Decode(data, Options{})
+}
+
+func bigInt(text string) *big.Int {
+	n, _ := new(big.Int).SetString(text, 10)
+	return n
+}
+
+// 示例来自 RFC 8949 附录 A
+func TestExamples(t *testing.T) {
+	for _, c := range []struct {
+		hex    string
+		expect interface{}
+	}{
+		{"00", uint64(0)},
+		{"17", uint64(23)},
+		{"1818", uint64(24)},
+		{"1b000000e8d4a51000", uint64(1000000000000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"3bffffffffffffffff", bigInt("-18446744073709551616")},
+		{"c249010000000000000000", bigInt("18446744073709551616")},
+		{"f90000", float32(0)},
+		{"f98000", float32(math.Copysign(0, -1))},
+		{"f93c00", float32(1)},
+		{"f97bff", float32(65504)},
+		{"f90001", float32(5.960464477539063e-8)},
+		{"f9c400", float32(-4)},
+		{"f97c00", float32(math.Inf(1))},
+		{"fa47c35000", float32(100000)},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", Undefined{}},
+		{"f0", Simple(16)},
+		{"f8ff", Simple(255)},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c11a514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c1fb41d452d9ec200000", time.Date(2013, 3, 21, 20, 4, 0, 5e8, time.UTC)},
+		{"d74401020304", Tag{23, []byte{1, 2, 3, 4}}},
+		{"d818456449455446", Tag{24, []byte("dIETF")}},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"62225c", "\"\\"},
+		{"63e6b0b4", "水"},
+		{"83010203", []interface{}{uint64(1), uint64(2), uint64(3)}},
+		{"8301820203820405", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+		{"a201020304", map[interface{}]interface{}{uint64(1): uint64(2), uint64(3): uint64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []interface{}{}},
+		{"9f018202039f0405ffff", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+		{"bf61610161629f0203ffff", map[interface{}]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}},
+	} {
+		re, err := decodeHex(t, c.hex)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", c.hex, err)
+			continue
+		}
+		if n, ok := c.expect.(*big.Int); ok {
+			if m, ok := re.(*big.Int); !ok || n.Cmp(m) != 0 {
+				t.Errorf("%s: Expect %v but %v", c.hex, n, re)
+			}
+			continue
+		}
+		if tm, ok := c.expect.(time.Time); ok {
+			if got, ok := re.(time.Time); !ok || !got.Equal(tm) {
+				t.Errorf("%s: Expect %v but %v", c.hex, tm, re)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(re, c.expect) {
+			t.Errorf("%s: Expect %#v but %#v", c.hex, c.expect, re)
+		}
+	}
+	re, err := decodeHex(t, "f97e00")
+	if f, ok := re.(float32); err != nil || !ok || !math.IsNaN(float64(f)) {
+		t.Errorf("f97e00: Expect NaN but %v, %v", re, err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, c := range []struct {
+		hex     string
+		offset  int
+		message string
+	}{
+		{"1c", 0, "reserved"},
+		{"1f", 0, "cannot be indefinite"},
+		{"ff", 0, "unexpected break"},
+		{"5f4101610aff", 3, "same type"},
+		{"6261ff", 0, "UTF-8"},
+		{"8201", 2, "eof"},
+		{"a20102", 3, "eof"},
+		{"a201020103", 3, "duplicate map key"},
+		{"a1810102", 1, "unhashable"},
+		{"c06161", 0, "RFC 3339"},
+		{"c241", 2, "eof"},
+		{"f818", 0, "short form"},
+		{"0000", 1, "trailing"},
+		{"c11bffffffffffffffff", 0, "out of range"},
+		{"c1fb7ff8000000000000", 0, "out of range"},
+		{"c1fb7ff0000000000000", 0, "out of range"},
+		{"c1fbfff0000000000000", 0, "out of range"},
+		{"c1f97e00", 0, "out of range"},
+		{"c1fb7e37e43c8800759c", 0, "out of range"},
+	} {
+		_, err := decodeHex(t, c.hex)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: Expect *ParseError but %v", c.hex, err)
+			continue
+		}
+		if e.Offset != c.offset || !strings.Contains(e.Message, c.message) {
+			t.Errorf("%s: Expect %s at %d but %v", c.hex, c.message, c.offset, e)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	data := []byte(strings.Repeat("\x81", 20) + "\x00")
+	if _, err := Decode(data, Options{MaxDepth: 20}); err != nil {
+		t.Fatalf("Expect success at depth 20 but %v", err)
+	}
+	_, err := Decode(data, Options{MaxDepth: 10})
+	if e, ok := err.(*ParseError); !ok || e.Offset != 10 || !strings.Contains(e.Message, "max depth") {
+		t.Fatalf("Expect max depth error at 10 but %v", err)
+	}
+	// 不定长数组组成的嵌套炸弹在达到上限时停止，而不是耗尽栈
+	bomb := []byte(strings.Repeat("\x9f", 100000))
+	if _, err := Decode(bomb, Options{}); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("Expect max depth error but %v", err)
+	}
+}
@@ -7,19 +7,31 @@ func Try(psc P) P {
 	return func(state State) (interface{}, error) {
 		tran := state.Begin()
 		re, err := psc.Parse(state)
-		if err == nil {
+		if err == nil || IsCut(err) {
 			state.Commit(tran)
-			return re, nil
+			return re, err
 		}
 		state.Rollback(tran)
 		return nil, err
 	}
 }
 
+// Ahead 尝试运行给定算子，无论成功与否都将 state 复位，即只向前查看而不消费输入。
+// psc 中的 Cut 不会传递到 Ahead 之外
+func Ahead(psc P) P {
+	return func(state State) (interface{}, error) {
+		tran := state.Begin()
+		re, err := uncut(psc).Parse(state)
+		state.Rollback(tran)
+		return re, err
+	}
+}
+
 // Choice 逐个尝试给定的算子，直到某个成功或者 state 无法复位，或者全部失败
 func Choice(Ps ...P) P {
 	return func(state State) (interface{}, error) {
 		var err error
+		var failures []Error
 		for _, p := range Ps {
 			var re interface{}
 			idx := state.Pos()
@@ -27,12 +39,15 @@ func Choice(Ps ...P) P {
 			if err == nil {
 				return re, nil
 			}
-			if state.Pos() != idx {
+			if state.Pos() != idx || IsCut(err) {
 				return nil, err
 			}
+			if e, ok := err.(Error); ok {
+				failures = append(failures, e)
+			}
 		}
 		//下面这个分支确保最后一个算子是 Fail 之类的零步进算子时，也能把错误信息传递出来。
-		return nil, err
+		return nil, mergeExpected(err, failures)
 	}
 }
 
@@ -46,6 +61,8 @@ func Many(psc P) P {
 			r, err := p.Parse(state)
 			if err == nil {
 				re = append(re, r)
+			} else if IsCut(err) {
+				return nil, err
 			} else {
 				break
 			}
@@ -74,6 +91,8 @@ func Many1(psc P) P {
 			r, err = p.Parse(state)
 			if err == nil {
 				re = append(re, r)
+			} else if IsCut(err) {
+				return nil, err
 			} else {
 				break
 			}
@@ -133,6 +152,9 @@ func Skip(p P) P {
 	return func(state State) (interface{}, error) {
 		for {
 			_, err := Try(p).Parse(state)
+			if IsCut(err) {
+				return nil, err
+			}
 			if err != nil {
 				return nil, nil
 			}
@@ -149,7 +171,7 @@ func Skip1(p P) P {
 // 可以用于边界检查。
 func FailIf(psc P) P {
 	message := fmt.Sprintf("Expect the P %v failed but it success.", psc)
-	return Choice(Try(psc).Then(Fail(message)), Return(nil))
+	return Choice(Try(uncut(psc)).Then(Fail(message)), Return(nil))
 }
 
 // Repeat 函数生成一个 P 算子，它匹配指定算子x到y次。
@@ -188,6 +210,9 @@ func UpTo(x int, psc P) P {
 		var re = make([]interface{}, 0, x)
 		for i := 0; i < x; i++ {
 			item, err := Try(psc).Parse(state)
+			if IsCut(err) {
+				return nil, err
+			}
 			if err != nil {
 				return re, nil
 			}
@@ -0,0 +1,244 @@
+// Package csv 提供按照 RFC 4180 语义解析 CSV/TSV 的算子。分隔符、引号、转义方式、
+// 注释前缀和行结束符都可以通过 Dialect 配置，以适应各种不规范的导出格式。
+//
+// 解析结果中的字段为 string ，记录为 []string ，整个文件为 [][]string 。
+package csv
+
+import (
+	"fmt"
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Terminator 指定记录的行结束符
+type Terminator int
+
+const (
+	// AnyEOL 接受 \r\n 、 \n 和单独的 \r
+	AnyEOL Terminator = iota
+	// CRLF 只接受 RFC 4180 规定的 \r\n
+	CRLF
+	// LF 只接受 \n
+	LF
+)
+
+// Dialect 描述一种 CSV 方言，零值即为以逗号分隔、双引号包围、两个引号表示一个引号的 RFC 4180 格式
+type Dialect struct {
+	// Delimiter 是字段分隔符，为 0 时使用 ','
+	Delimiter rune
+	// Quote 是包围字段的引号，为 0 时使用 '"'
+	Quote rune
+	// NoQuote 为 true 时不处理引号，引号作为普通字符
+	NoQuote bool
+	// Escape 是引号内的转义字符，它后面的字符按原样保留。为 0 时按照 RFC 4180 以两个引号表示一个引号
+	Escape rune
+	// Comment 非空时，以它开头的行作为注释跳过
+	Comment string
+	// Terminator 指定记录的行结束符
+	Terminator Terminator
+	// TrimLeadingSpace 为 true 时忽略字段开头的空格和制表符
+	TrimLeadingSpace bool
+	// LazyQuotes 为 true 时允许未加引号的字段中出现引号
+	LazyQuotes bool
+	// FieldsPerRecord 为正数时要求每条记录都有这么多字段，为 0 时以第一条记录的字段数为准，
+	// 为负数时不检查
+	FieldsPerRecord int
+}
+
+// RFC4180 是严格的 RFC 4180 方言，记录必须以 \r\n 结束
+var RFC4180 = Dialect{Terminator: CRLF}
+
+// TSV 是以制表符分隔、不处理引号的方言
+var TSV = Dialect{Delimiter: '\t', NoQuote: true}
+
+func (d Dialect) normalize() Dialect {
+	if d.Delimiter == 0 {
+		d.Delimiter = ','
+	}
+	if d.Quote == 0 {
+		d.Quote = '"'
+	}
+	return d
+}
+
+// eol 匹配一个行结束符
+func (d Dialect) eol() goP2.P {
+	switch d.Terminator {
+	case CRLF:
+		return goP2.Str("\r\n")
+	case LF:
+		return goP2.Chr('\n')
+	}
+	return goP2.Choice(goP2.Try(goP2.Str("\r\n")), goP2.Try(goP2.RuneOf("\r\n")))
+}
+
+// ParseError 是带有行列位置的解析错误，行列都从 1 开始
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("csv: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func parseError(data []rune, err error) *ParseError {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return &ParseError{1, 1, err.Error()}
+	}
+	at := goP2.LineCol(data, e.Pos)
+	return &ParseError{at.Line, at.Column, e.Message}
+}
+
+// QuotedField 解析一个由引号包围的字段，字段内可以包含分隔符和换行
+func QuotedField(d Dialect) goP2.P {
+	d = d.normalize()
+	quote := goP2.Chr(d.Quote)
+	var item goP2.P
+	if d.Escape == 0 {
+		doubled := goP2.Try(goP2.Str(string([]rune{d.Quote, d.Quote}))).Then(goP2.Return(d.Quote))
+		item = goP2.Choice(doubled, goP2.RuneNone(string([]rune{d.Quote})))
+	} else {
+		escaped := goP2.Try(goP2.Chr(d.Escape).Then(goP2.One))
+		item = goP2.Choice(escaped, goP2.RuneNone(string([]rune{d.Quote, d.Escape})))
+	}
+	closing := goP2.Do(func(state goP2.State) interface{} {
+		if _, err := quote.Parse(state); err != nil {
+			panic(state.Trap("unterminated quoted field"))
+		}
+		return nil
+	})
+	return goP2.Between(quote, closing, goP2.Many(item)).Bind(goP2.ReturnString)
+}
+
+// UnquotedField 解析一个没有引号的字段，它在分隔符、换行或者输入结束处停止
+func UnquotedField(d Dialect) goP2.P {
+	d = d.normalize()
+	stop := string([]rune{d.Delimiter, '\r', '\n'})
+	if !d.NoQuote && !d.LazyQuotes {
+		stop += string([]rune{d.Quote})
+	}
+	return goP2.Many(goP2.RuneNone(stop)).Bind(goP2.ReturnString)
+}
+
+// Field 解析一个字段，以引号开头时按照 QuotedField 解析，否则按照 UnquotedField 解析
+func Field(d Dialect) goP2.P {
+	d = d.normalize()
+	space := goP2.Return(nil)
+	if d.TrimLeadingSpace {
+		space = goP2.Skip(goP2.RuneOf(strings.Replace(" \t", string([]rune{d.Delimiter}), "", -1)))
+	}
+	unquoted := UnquotedField(d)
+	if d.NoQuote {
+		return space.Then(unquoted)
+	}
+	quoted := QuotedField(d)
+	return space.Then(goP2.Choice(goP2.Ahead(goP2.Chr(d.Quote)).Then(quoted), unquoted))
+}
+
+// Record 解析一条记录并消费其后的行结束符，在输入结尾处行结束符可以省略
+func Record(d Dialect) goP2.P {
+	d = d.normalize()
+	field := Field(d)
+	delimiter := goP2.Try(goP2.Chr(d.Delimiter))
+	eol := d.eol()
+	return goP2.Do(func(state goP2.State) interface{} {
+		re := []string{field.Exec(state).(string)}
+		for {
+			if _, err := delimiter.Parse(state); err != nil {
+				break
+			}
+			re = append(re, field.Exec(state).(string))
+		}
+		if _, err := goP2.Try(goP2.EOF).Parse(state); err == nil {
+			return re
+		}
+		pos := state.Pos()
+		if _, err := goP2.Try(eol).Parse(state); err != nil {
+			x, _ := state.Next()
+			state.SeekTo(pos)
+			panic(state.Trap("Expect delimiter or line end but %q", x))
+		}
+		return re
+	})
+}
+
+// skip 跳过空行和注释行，返回是否到达输入结尾
+func (d Dialect) skip(state goP2.State) bool {
+	eol := goP2.Try(d.eol())
+	var comment goP2.P
+	if d.Comment != "" {
+		comment = goP2.Try(goP2.Str(d.Comment).Then(goP2.Skip(goP2.RuneNone("\r\n"))))
+	}
+	for {
+		if _, err := goP2.Try(goP2.EOF).Parse(state); err == nil {
+			return true
+		}
+		if _, err := eol.Parse(state); err == nil {
+			continue
+		}
+		if comment != nil {
+			if _, err := comment.Parse(state); err == nil {
+				continue
+			}
+		}
+		return false
+	}
+}
+
+// Each 从 state 中逐条读取记录并交给 fn 处理，实现流式解析。 fn 返回的错误会中止读取并原样返回。
+// 空行和注释行会被跳过。
+func Each(d Dialect, state goP2.State, fn func(record []string) error) error {
+	d = d.normalize()
+	record := Record(d)
+	fields := d.FieldsPerRecord
+	for {
+		if d.skip(state) {
+			return nil
+		}
+		start := state.Pos()
+		x, err := record.Parse(state)
+		if err != nil {
+			return err
+		}
+		re := x.([]string)
+		if fields == 0 {
+			fields = len(re)
+		}
+		if fields > 0 && len(re) != fields {
+			state.SeekTo(start)
+			return state.Trap("wrong number of fields: expect %d but %d", fields, len(re))
+		}
+		if err := fn(re); err != nil {
+			return err
+		}
+	}
+}
+
+// File 解析整个输入，返回全部记录
+func File(d Dialect) goP2.P {
+	return func(state goP2.State) (interface{}, error) {
+		re := [][]string{}
+		err := Each(d, state, func(record []string) error {
+			re = append(re, record)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return re, nil
+	}
+}
+
+// Parse 按照方言 d 解析文本，错误总是 *ParseError
+func Parse(text string, d Dialect) ([][]string, error) {
+	state := goP2.BasicStateFromText(text)
+	re, err := File(d).Parse(&state)
+	if err != nil {
+		return nil, parseError([]rune(text), err)
+	}
+	return re.([][]string), nil
+}
@@ -0,0 +1,126 @@
+package csv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func TestParse(t *testing.T) {
+	text := "name,comment\r\n\"Smith, J\",\"said \"\"hi\"\"\r\nthen left\"\r\nDoe,\r\n"
+	re, err := Parse(text, RFC4180)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := [][]string{
+		{"name", "comment"},
+		{"Smith, J", "said \"hi\"\r\nthen left"},
+		{"Doe", ""},
+	}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %q but %q", expect, re)
+	}
+}
+
+func TestParseLineEndings(t *testing.T) {
+	re, err := Parse("a,b\nc,d\re,f", Dialect{})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %q but %q", expect, re)
+	}
+	if _, err := Parse("a,b\nc,d\r\n", RFC4180); err == nil {
+		t.Fatalf("Expect bare \\n rejected by RFC4180")
+	}
+}
+
+func TestDialect(t *testing.T) {
+	d := Dialect{Delimiter: ';', Quote: '\'', Escape: '\\', Comment: "#", TrimLeadingSpace: true}
+	text := "# exported\n'a\\'b'; c\n\n#skip\n  'x;y';z"
+	re, err := Parse(text, d)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := [][]string{{"a'b", "c"}, {"x;y", "z"}}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %q but %q", expect, re)
+	}
+}
+
+func TestTSV(t *testing.T) {
+	re, err := Parse("id\tname\n1\t\"quoted\"\n", TSV)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := [][]string{{"id", "name"}, {"1", "\"quoted\""}}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %q but %q", expect, re)
+	}
+}
+
+func TestLazyQuotes(t *testing.T) {
+	if _, err := Parse("a\"b,c", Dialect{}); err == nil {
+		t.Fatalf("Expect bare quote rejected")
+	}
+	re, err := Parse("a\"b,c", Dialect{LazyQuotes: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if !reflect.DeepEqual(re, [][]string{{"a\"b", "c"}}) {
+		t.Fatalf("Expect a\"b,c but %q", re)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("a,b\nc,\"d\n", Dialect{})
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Expect *ParseError but %#v", err)
+	}
+	if e.Line != 3 || !strings.Contains(e.Message, "unterminated") {
+		t.Fatalf("Expect unterminated quoted field at line 3 but %v", e)
+	}
+	_, err = Parse("a,b\nc\n", Dialect{})
+	e, ok = err.(*ParseError)
+	if !ok || e.Line != 2 || e.Column != 1 {
+		t.Fatalf("Expect wrong number of fields at line 2, column 1 but %v", err)
+	}
+	if _, err := Parse("a,b\nc\n", Dialect{FieldsPerRecord: -1}); err != nil {
+		t.Fatalf("Expect variable fields accepted but %v", err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	state := goP2.BasicStateFromText("1,2\n3,4\n5,6\n")
+	stop := errors.New("stop")
+	var seen [][]string
+	err := Each(Dialect{}, &state, func(record []string) error {
+		seen = append(seen, record)
+		if len(seen) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("Expect stop error but %v", err)
+	}
+	if !reflect.DeepEqual(seen, [][]string{{"1", "2"}, {"3", "4"}}) {
+		t.Fatalf("Expect first two records but %q", seen)
+	}
+}
+
+func TestField(t *testing.T) {
+	state := goP2.BasicStateFromText(`"a,b",c`)
+	re, err := Field(Dialect{}).Parse(&state)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if re != "a,b" {
+		t.Fatalf("Expect \"a,b\" but %q", re)
+	}
+}
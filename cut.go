@@ -0,0 +1,42 @@
+package goP2
+
+// Cut 运行 p ，并把 p 的失败标记为不可回溯：外层的 Try 不再复位， Choice 不再尝试其它分支，
+// Many 、 Skip 、 UpTo 之类的重复算子也不再把它当作重复的结束，而是直接返回错误，
+// 所以错误停留在真正出错的位置，而不是某个外层分支的开头。
+//
+// 通常在确定了分支之后使用，例如 Str("if").Then(Cut(condition.Then(body))) 在读到 if 之后
+// 就不再尝试其它的语句。类似 Prolog 的 cut 和 pyparsing 的 - 运算符，标记对所有外层的算子都有效，
+// 只有 Ahead 和 FailIf 这样总是复位的算子会清除它。
+// p 返回的错误不是 Error 时，转换为当前位置上信息相同的 Error
+func Cut(p P) P {
+	return func(state State) (interface{}, error) {
+		re, err := p(state)
+		if err == nil {
+			return re, nil
+		}
+		e, ok := err.(Error)
+		if !ok {
+			e = Error{Pos: state.Pos(), Message: err.Error()}
+		}
+		e.Cut = true
+		return nil, e
+	}
+}
+
+// IsCut 判断 err 是否发生在 Cut 之内
+func IsCut(err error) bool {
+	e, ok := err.(Error)
+	return ok && e.Cut
+}
+
+// uncut 运行 p 并清除错误的 Cut 标记，用于 Ahead 和 FailIf 这样总是复位的算子，其中的 Cut 不影响外层的回溯
+func uncut(p P) P {
+	return func(state State) (interface{}, error) {
+		re, err := p(state)
+		if e, ok := err.(Error); ok && e.Cut {
+			e.Cut = false
+			return nil, e
+		}
+		return re, err
+	}
+}
@@ -0,0 +1,87 @@
+package goP2
+
+import (
+	"testing"
+)
+
+// statement 是 "if x;" 、 "let x;" 或者表达式 "x;" ，关键字之后用 Cut 确定分支
+func statement(cut bool) P {
+	commit := func(p P) P { return p }
+	if cut {
+		commit = Cut
+	}
+	name := Many1(RuneOf("abcxyz"))
+	ifStmt := Try(Str("if ").Then(commit(name.Then(Chr(';')))))
+	letStmt := Try(Str("let ").Then(commit(name.Then(Chr(';')))))
+	expr := name.Then(Chr(';'))
+	return Choice(ifStmt, letStmt, expr)
+}
+
+func TestCut(t *testing.T) {
+	for _, text := range []string{"if a;", "let b;", "c;"} {
+		state := BasicStateFromText(text)
+		if _, err := Many1(statement(true)).Over(EOF).Parse(&state); err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+	}
+
+	// 没有 Cut 时 if 分支的错误被回溯， Many 在 if 之前停止，错误由 EOF 报告在 if 的开头
+	state := BasicStateFromText("let a;if b!")
+	_, err := Many(statement(false)).Over(EOF).Parse(&state)
+	if e := err.(Error); e.Pos != 7 || e.Cut {
+		t.Fatalf("Expect error at 7 but %#v", e)
+	}
+
+	state = BasicStateFromText("let a;if b!")
+	_, err = Many(statement(true)).Over(EOF).Parse(&state)
+	e, ok := err.(Error)
+	if !ok || !e.Cut || e.Pos != 11 || e.Message != "Expect ';' but '!'" {
+		t.Fatalf("Expect cut error at 11 but %#v", err)
+	}
+	if state.Pos() != 11 {
+		t.Fatalf("Expect the state to stay at 11 but %d", state.Pos())
+	}
+}
+
+func TestCutRepetition(t *testing.T) {
+	item := Chr('[').Then(Cut(Chr('x').Then(Chr(']'))))
+	for name, p := range map[string]P{
+		"Many":  Many(item),
+		"Many1": Many1(item),
+		"Skip":  Skip(item),
+		"UpTo":  UpTo(3, item),
+		"Label": Label(Cut(Fail("inner")), "item"),
+	} {
+		state := BasicStateFromText("[x][y]")
+		if _, err := p.Parse(&state); !IsCut(err) {
+			t.Fatalf("%s: expect cut error but %v", name, err)
+		}
+	}
+	state := BasicStateFromText("[x]")
+	if _, err := Many(item).Over(EOF).Parse(&state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCutLookahead(t *testing.T) {
+	cut := Str("a").Then(Cut(Chr('b')))
+
+	state := BasicStateFromText("ac")
+	if _, err := FailIf(cut).Parse(&state); err != nil {
+		t.Fatalf("Expect FailIf to succeed but %v", err)
+	}
+	if state.Pos() != 0 {
+		t.Fatalf("Expect the state to stay at 0 but %d", state.Pos())
+	}
+
+	state = BasicStateFromText("ac")
+	re, err := Choice(Ahead(cut), Str("ac")).Parse(&state)
+	if err != nil || re != "ac" {
+		t.Fatalf("Expect the alternative ac but %v, %v", re, err)
+	}
+
+	state = BasicStateFromText("ac")
+	if _, err := Ahead(cut).Parse(&state); err == nil || IsCut(err) || state.Pos() != 0 {
+		t.Fatalf("Expect a plain error at 0 but %#v at %d", err, state.Pos())
+	}
+}
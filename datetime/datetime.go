@@ -0,0 +1,260 @@
+// Package datetime 提供日期时间的解析算子：RFC 3339 、 ISO 8601 （日历日期、周日期、序数日期和时长）、
+// RFC 1123 以及 Apache/nginx 日志中的时间戳。
+//
+// 与 time.Parse 的布局字符串不同，这些算子可以直接组合进更大的 goP2 文法中，
+// 它们只消费时间戳本身，之后的输入留给外层文法处理。
+package datetime
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+const decimal = "0123456789"
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// accept 在下一个字符属于 set 时消费并返回它，否则不消费输入
+func accept(state goP2.State, set string) (rune, bool) {
+	x, err := goP2.Try(goP2.RuneOf(set)).Parse(state)
+	if err != nil {
+		return 0, false
+	}
+	return x.(rune), true
+}
+
+// Fixed 返回解析 n 位十进制数字的算子，结果为 int
+func Fixed(n int) goP2.P {
+	return goP2.Times(n, goP2.RuneOf(decimal)).Bind(func(x interface{}) goP2.P {
+		v, _ := strconv.Atoi(goP2.ToString(x))
+		return goP2.Return(v)
+	})
+}
+
+func fixed(state goP2.State, n int) int {
+	return Fixed(n).Exec(state).(int)
+}
+
+// fraction 解析小数点（或者 ISO 8601 允许的逗号）及其之后的数字，返回纳秒数，超过纳秒的精度被截断。
+// 分隔符之后没有数字时不消费输入
+func fraction(state goP2.State, separators string) int {
+	x, err := goP2.Try(goP2.RuneOf(separators).Then(goP2.Many1(goP2.RuneOf(decimal)))).Parse(state)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.Atoi((goP2.ToString(x) + "000000000")[:9])
+	return v
+}
+
+// checkDate 检查年月日是否有效
+func checkDate(state goP2.State, pos, year int, month time.Month, day int) {
+	if month < time.January || month > time.December {
+		fail(state, pos, "month %d out of range", month)
+	}
+	// 下个月的第 0 天即为本月的最后一天
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day < 1 || day > last {
+		fail(state, pos, "day %d out of range for %d-%02d", day, year, month)
+	}
+}
+
+func checkClock(state goP2.State, pos, hour, minute, second int) {
+	if hour > 23 || minute > 59 || second > 59 {
+		fail(state, pos, "time %02d:%02d:%02d out of range", hour, minute, second)
+	}
+}
+
+// Zone 解析 Z 、 ±hh:mm 、 ±hhmm 或者 ±hh 形式的时区偏移，结果为 *time.Location
+var Zone = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	if _, ok := accept(state, "Zz"); ok {
+		return time.UTC
+	}
+	sign := goP2.RuneOf("+-").Exec(state).(rune)
+	hour := fixed(state, 2)
+	minute := 0
+	if _, ok := accept(state, ":"); ok {
+		minute = fixed(state, 2)
+	} else if m, err := goP2.Try(Fixed(2)).Parse(state); err == nil {
+		minute = m.(int)
+	}
+	if hour > 23 || minute > 59 {
+		fail(state, pos, "time zone offset out of range")
+	}
+	offset := hour*3600 + minute*60
+	if sign == '-' {
+		offset = -offset
+	}
+	return time.FixedZone("", offset)
+})
+
+// RFC3339 解析 RFC 3339 的 date-time ，例如 2006-01-02T15:04:05.999Z07:00 ，日期和时间之间
+// 可以使用 T 、 t 或者空格，结果为 time.Time
+var RFC3339 = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	year := fixed(state, 4)
+	goP2.Chr('-').Exec(state)
+	month := time.Month(fixed(state, 2))
+	goP2.Chr('-').Exec(state)
+	day := fixed(state, 2)
+	checkDate(state, pos, year, month, day)
+	goP2.RuneOf("Tt ").Exec(state)
+	hour, minute, second := clock(state)
+	nsec := fraction(state, ".")
+	var loc *time.Location
+	if _, ok := accept(state, "Zz"); ok {
+		loc = time.UTC
+	} else {
+		zone := state.Pos()
+		sign := goP2.RuneOf("+-").Exec(state).(rune)
+		h := fixed(state, 2)
+		goP2.Chr(':').Exec(state)
+		m := fixed(state, 2)
+		if h > 23 || m > 59 {
+			fail(state, zone, "time zone offset out of range")
+		}
+		offset := h*3600 + m*60
+		if sign == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	return time.Date(year, month, day, hour, minute, second, nsec, loc)
+})
+
+var monthNames = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+var weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// oneOfNames 按照给定的名字列表匹配，结果为名字的下标
+func oneOfNames(names []string) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		pos := state.Pos()
+		text := goP2.ToString(goP2.Times(3, goP2.P(goP2.Letter)).Exec(state))
+		for i, name := range names {
+			if strings.EqualFold(text, name) {
+				return i
+			}
+		}
+		fail(state, pos, "Expect one of %s but %s", strings.Join(names, ","), text)
+		return nil
+	})
+}
+
+// MonthAbbr 解析英文月份的三字母缩写 Jan 到 Dec ，不区分大小写，结果为 time.Month
+var MonthAbbr = oneOfNames(monthNames).Bind(func(x interface{}) goP2.P {
+	return goP2.Return(time.Month(x.(int) + 1))
+})
+
+// WeekdayAbbr 解析英文星期的三字母缩写 Sun 到 Sat ，不区分大小写，结果为 time.Weekday
+var WeekdayAbbr = oneOfNames(weekdayNames).Bind(func(x interface{}) goP2.P {
+	return goP2.Return(time.Weekday(x.(int)))
+})
+
+// zoneNames 是 RFC 822 定义的时区缩写
+var zoneNames = map[string]int{
+	"UT": 0, "GMT": 0, "UTC": 0,
+	"EST": -5, "EDT": -4, "CST": -6, "CDT": -5, "MST": -7, "MDT": -6, "PST": -8, "PDT": -7,
+}
+
+// numericZone 解析 ±hhmm 形式的时区
+var numericZone = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	sign := goP2.RuneOf("+-").Exec(state).(rune)
+	h := fixed(state, 2)
+	m := fixed(state, 2)
+	if h > 23 || m > 59 {
+		fail(state, pos, "time zone offset out of range")
+	}
+	offset := h*3600 + m*60
+	if sign == '-' {
+		offset = -offset
+	}
+	return time.FixedZone("", offset)
+})
+
+// clock 解析 hh:mm:ss
+func clock(state goP2.State) (int, int, int) {
+	pos := state.Pos()
+	hour := fixed(state, 2)
+	goP2.Chr(':').Exec(state)
+	minute := fixed(state, 2)
+	goP2.Chr(':').Exec(state)
+	second := fixed(state, 2)
+	checkClock(state, pos, hour, minute, second)
+	return hour, minute, second
+}
+
+// RFC1123 解析 RFC 1123 （HTTP 日期）形式的时间，例如 Mon, 02 Jan 2006 15:04:05 GMT ，
+// 时区可以是 RFC 822 的时区缩写或者 -0700 形式的偏移。星期必须与日期相符，结果为 time.Time
+var RFC1123 = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	weekday := WeekdayAbbr.Exec(state).(time.Weekday)
+	goP2.Str(", ").Exec(state)
+	day := fixed(state, 2)
+	goP2.Chr(' ').Exec(state)
+	month := MonthAbbr.Exec(state).(time.Month)
+	goP2.Chr(' ').Exec(state)
+	year := fixed(state, 4)
+	checkDate(state, pos, year, month, day)
+	goP2.Chr(' ').Exec(state)
+	hour, minute, second := clock(state)
+	goP2.Chr(' ').Exec(state)
+	var loc *time.Location
+	if l, err := goP2.Try(numericZone).Parse(state); err == nil {
+		loc = l.(*time.Location)
+	} else {
+		zone := state.Pos()
+		name := goP2.ToString(goP2.Many1(goP2.RuneOf("ABCDEFGHIJKLMNOPQRSTUVWXYZ")).Exec(state))
+		offset, ok := zoneNames[name]
+		if !ok {
+			fail(state, zone, "unknown time zone %s", name)
+		}
+		loc = time.FixedZone(name, offset*3600)
+	}
+	re := time.Date(year, month, day, hour, minute, second, 0, loc)
+	if re.Weekday() != weekday {
+		fail(state, pos, "weekday %s does not match %s", weekday, re.Format("2006-01-02"))
+	}
+	return re
+})
+
+// CommonLog 解析 Apache/nginx 访问日志中的时间戳，例如 10/Oct/2000:13:55:36 -0700 ，
+// 日志中包围它的方括号由外层文法处理，结果为 time.Time
+var CommonLog = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	day := fixed(state, 2)
+	goP2.Chr('/').Exec(state)
+	month := MonthAbbr.Exec(state).(time.Month)
+	goP2.Chr('/').Exec(state)
+	year := fixed(state, 4)
+	checkDate(state, pos, year, month, day)
+	goP2.Chr(':').Exec(state)
+	hour, minute, second := clock(state)
+	goP2.Chr(' ').Exec(state)
+	loc := numericZone.Exec(state).(*time.Location)
+	return time.Date(year, month, day, hour, minute, second, 0, loc)
+})
+
+// NginxError 解析 nginx 错误日志中的本地时间，例如 2000/10/10 13:55:36 ，结果为 loc 时区中的 time.Time
+func NginxError(loc *time.Location) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		pos := state.Pos()
+		year := fixed(state, 4)
+		goP2.Chr('/').Exec(state)
+		month := time.Month(fixed(state, 2))
+		goP2.Chr('/').Exec(state)
+		day := fixed(state, 2)
+		checkDate(state, pos, year, month, day)
+		goP2.Chr(' ').Exec(state)
+		hour, minute, second := clock(state)
+		return time.Date(year, month, day, hour, minute, second, 0, loc)
+	})
+}
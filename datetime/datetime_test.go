@@ -0,0 +1,118 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func parse(p goP2.P, text string) (interface{}, error) {
+	state := goP2.BasicStateFromText(text)
+	return p.Over(goP2.EOF).Parse(&state)
+}
+
+func TestTimestamps(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	for _, c := range []struct {
+		p      goP2.P
+		text   string
+		expect time.Time
+	}{
+		{RFC3339, "2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{RFC3339, "2006-01-02t15:04:05.123456789123-07:00", time.Date(2006, 1, 2, 22, 4, 5, 123456789, time.UTC)},
+		{RFC3339, "2024-02-29 00:00:00+05:30", time.Date(2024, 2, 28, 18, 30, 0, 0, time.UTC)},
+		{RFC1123, "Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{RFC1123, "Mon, 02 Jan 2006 10:04:05 EST", time.Date(2006, 1, 2, 10, 4, 5, 0, est)},
+		{RFC1123, "Mon, 02 Jan 2006 08:04:05 -0700", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{CommonLog, "10/Oct/2000:13:55:36 -0700", time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC)},
+		{NginxError(time.UTC), "2000/10/10 13:55:36", time.Date(2000, 10, 10, 13, 55, 36, 0, time.UTC)},
+		{ISO8601, "2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ISO8601, "20060102T150405Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{ISO8601, "2006-01-02T15:04:05,5+01", time.Date(2006, 1, 2, 14, 4, 5, 5e8, time.UTC)},
+		{ISO8601, "2006-01-02T15:30.5", time.Date(2006, 1, 2, 15, 30, 30, 0, time.UTC)},
+		{ISO8601, "2009-W01-1", time.Date(2008, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{ISO8601, "2004W537", time.Date(2005, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ISO8601, "2008-366T12", time.Date(2008, 12, 31, 12, 0, 0, 0, time.UTC)},
+		{ISO8601, "1981095", time.Date(1981, 4, 5, 0, 0, 0, 0, time.UTC)},
+	} {
+		re, err := parse(c.p, c.text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", c.text, err)
+			continue
+		}
+		if !re.(time.Time).Equal(c.expect) {
+			t.Errorf("%s: Expect %v but %v", c.text, c.expect, re)
+		}
+	}
+}
+
+func TestInvalidTimestamps(t *testing.T) {
+	for _, c := range []struct {
+		p    goP2.P
+		text string
+	}{
+		{RFC3339, "2006-02-29T00:00:00Z"},
+		{RFC3339, "2006-01-02T24:00:00Z"},
+		{RFC3339, "2006-01-02T15:04:05"},
+		{RFC3339, "2006-01-02T15:04:05+0700"},
+		{RFC1123, "Tue, 02 Jan 2006 15:04:05 GMT"},
+		{RFC1123, "Mon, 02 Jan 2006 15:04:05 XYZ"},
+		{CommonLog, "10/Okt/2000:13:55:36 -0700"},
+		{ISO8601, "2005-W53-1"},
+		{ISO8601, "2006-366"},
+		{ISO8601, "2006-13-01"},
+		{ISO8601, "2006-01-02T15:60"},
+	} {
+		if re, err := parse(c.p, c.text); err == nil {
+			t.Errorf("%s: Expect error but %v", c.text, re)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	re, err := parse(ISOPeriod, "P1Y2M10DT2H30M1.5S")
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	p := re.(Period)
+	if p != (Period{Years: 1, Months: 2, Days: 10, Hours: 2, Minutes: 30, Seconds: 1, Nanoseconds: 5e8}) {
+		t.Fatalf("Expect P1Y2M10DT2H30M1.5S but %+v", p)
+	}
+	start := time.Date(2006, 1, 31, 0, 0, 0, 0, time.UTC)
+	if got := p.AddTo(start); !got.Equal(time.Date(2007, 4, 10, 2, 30, 1, 5e8, time.UTC)) {
+		t.Fatalf("Expect 2007-04-10T02:30:01.5Z but %v", got)
+	}
+	for text, expect := range map[string]time.Duration{
+		"P3W":    21 * 24 * time.Hour,
+		"PT0.5S": 500 * time.Millisecond,
+		"P1DT1M": 24*time.Hour + time.Minute,
+		"PT36H":  36 * time.Hour,
+	} {
+		d, err := parse(Duration, text)
+		if err != nil || d.(time.Duration) != expect {
+			t.Errorf("%s: Expect %v but %v, %v", text, expect, d, err)
+		}
+	}
+	for _, text := range []string{"P", "PT", "P1M", "P1D2Y", "P1.5D", "P1H"} {
+		if d, err := parse(Duration, text); err == nil {
+			t.Errorf("%s: Expect error but %v", text, d)
+		}
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	// 时间戳可以作为更大文法中的一部分
+	line := goP2.Between(goP2.Chr('['), goP2.Chr(']'), CommonLog).Over(goP2.Str(` "GET /"`))
+	re, err := parse(line, `[10/Oct/2000:13:55:36 +0000] "GET /"`)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if !re.(time.Time).Equal(time.Date(2000, 10, 10, 13, 55, 36, 0, time.UTC)) {
+		t.Fatalf("Expect 2000-10-10T13:55:36Z but %v", re)
+	}
+	state := goP2.BasicStateFromText("2006-01-02T15:04:05Z,next")
+	if _, err := RFC3339.Over(goP2.Str(",next")).Parse(&state); err != nil {
+		t.Fatalf("Expect RFC3339 to stop before ',' but %v", err)
+	}
+}
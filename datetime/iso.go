@@ -0,0 +1,228 @@
+package datetime
+
+import (
+	"strconv"
+	"time"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// digitCount 向前查看接下来连续数字的个数，不消费输入
+func digitCount(state goP2.State) int {
+	x, _ := goP2.Ahead(goP2.Many(goP2.RuneOf(decimal))).Parse(state)
+	return len(x.([]interface{}))
+}
+
+// weekDate 返回 ISO 周日期对应的日历日期，第 1 周是包含 1 月 4 日的那一周
+func weekDate(year, week, day int) time.Time {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	return jan4.AddDate(0, 0, (week-1)*7+day-1-offset)
+}
+
+// weeksIn 返回 year 的 ISO 周数， 12 月 28 日总是在最后一周
+func weeksIn(year int) int {
+	_, w := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	return w
+}
+
+// isoDate 解析 ISO 8601 的日历日期、周日期或者序数日期，基本格式和扩展格式都可以，
+// 返回 UTC 零点的 time.Time
+func isoDate(state goP2.State) time.Time {
+	pos := state.Pos()
+	year := fixed(state, 4)
+	_, extended := accept(state, "-")
+	if _, ok := accept(state, "W"); ok {
+		week := fixed(state, 2)
+		if extended {
+			goP2.Chr('-').Exec(state)
+		}
+		day := fixed(state, 1)
+		if week < 1 || week > weeksIn(year) || day < 1 || day > 7 {
+			fail(state, pos, "week date %04d-W%02d-%d out of range", year, week, day)
+		}
+		return weekDate(year, week, day)
+	}
+	// 序数日期是三位数字，日历日期是两位月份加两位日期
+	if digitCount(state) == 3 {
+		day := fixed(state, 3)
+		last := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		if day < 1 || day > last {
+			fail(state, pos, "ordinal date %04d-%03d out of range", year, day)
+		}
+		return time.Date(year, time.January, day, 0, 0, 0, 0, time.UTC)
+	}
+	month := time.Month(fixed(state, 2))
+	if extended {
+		goP2.Chr('-').Exec(state)
+	}
+	day := fixed(state, 2)
+	checkDate(state, pos, year, month, day)
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ISODate 解析 ISO 8601 的日期，可以是日历日期 2006-01-02 、周日期 2006-W01-1 或者序数日期
+// 2006-002 ，以及对应的基本格式 20060102 、 2006W011 和 2006002 ，结果为 UTC 零点的 time.Time
+var ISODate = goP2.Do(func(state goP2.State) interface{} {
+	return isoDate(state)
+})
+
+// isoTime 解析 hh[:mm[:ss]] 或者基本格式 hh[mm[ss]] ，最低位的分量可以带有以 . 或 , 开始的小数，
+// 返回从零点开始的时长
+func isoTime(state goP2.State) time.Duration {
+	pos := state.Pos()
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	limits := []int{23, 59, 59}
+	var re time.Duration
+	extended := false
+	for i, unit := range units {
+		if i == 1 {
+			if _, ok := accept(state, ":"); ok {
+				extended = true
+			} else if digitCount(state) < 2 {
+				break
+			}
+		} else if i == 2 {
+			if extended {
+				if _, ok := accept(state, ":"); !ok {
+					break
+				}
+			} else if digitCount(state) < 2 {
+				break
+			}
+		}
+		v := fixed(state, 2)
+		if v > limits[i] {
+			fail(state, pos, "time component %02d out of range", v)
+		}
+		re += time.Duration(v) * unit
+		if nsec := fraction(state, ".,"); nsec > 0 {
+			re += unit / time.Second * time.Duration(nsec)
+			break
+		}
+	}
+	return re
+}
+
+// ISOTime 解析 ISO 8601 的时刻 hh:mm:ss.sss 或者基本格式 hhmmss.sss ，分和秒可以省略，
+// 最低位的分量可以带有小数，结果为从零点开始的 time.Duration
+var ISOTime = goP2.Do(func(state goP2.State) interface{} {
+	return isoTime(state)
+})
+
+// ISO8601In 返回解析 ISO 8601 日期时间的算子：日期之后可以跟 T 和时刻，时刻之后可以跟时区，
+// 没有时区的时间视为 loc 中的本地时间，结果为 time.Time
+func ISO8601In(loc *time.Location) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		date := isoDate(state)
+		if _, ok := accept(state, "T"); !ok {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		}
+		clock := isoTime(state)
+		zone := loc
+		if z, err := goP2.Try(Zone).Parse(state); err == nil {
+			zone = z.(*time.Location)
+		}
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, zone).Add(clock)
+	})
+}
+
+// ISO8601 解析 ISO 8601 日期时间，没有时区的时间视为 UTC
+var ISO8601 = ISO8601In(time.UTC)
+
+// Period 是 ISO 8601 时长 PnYnMnWnDTnHnMnS 的各个分量，年和月没有固定的长度，
+// 所以保留为日历分量而不是直接换算成 time.Duration
+type Period struct {
+	Years   int
+	Months  int
+	Weeks   int
+	Days    int
+	Hours   int
+	Minutes int
+	Seconds int
+	// Nanoseconds 是秒的小数部分
+	Nanoseconds int
+}
+
+// Duration 将时长换算为 time.Duration ，一天按 24 小时计。含有年或者月时返回 false
+func (p Period) Duration() (time.Duration, bool) {
+	if p.Years != 0 || p.Months != 0 {
+		return 0, false
+	}
+	hours := time.Duration((p.Weeks*7+p.Days)*24 + p.Hours)
+	return hours*time.Hour + time.Duration(p.Minutes)*time.Minute +
+		time.Duration(p.Seconds)*time.Second + time.Duration(p.Nanoseconds), true
+}
+
+// AddTo 按照日历把时长加到 t 上
+func (p Period) AddTo(t time.Time) time.Time {
+	t = t.AddDate(p.Years, p.Months, p.Weeks*7+p.Days)
+	return t.Add(time.Duration(p.Hours)*time.Hour + time.Duration(p.Minutes)*time.Minute +
+		time.Duration(p.Seconds)*time.Second + time.Duration(p.Nanoseconds))
+}
+
+// ISOPeriod 解析 ISO 8601 时长，例如 P1Y2M10DT2H30M 、 P3W 或者 PT0.5S ，只有秒可以带小数，
+// 结果为 Period
+var ISOPeriod = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	goP2.Chr('P').Exec(state)
+	var re Period
+	dateFields := map[rune]*int{'Y': &re.Years, 'M': &re.Months, 'W': &re.Weeks, 'D': &re.Days}
+	timeFields := map[rune]*int{'H': &re.Hours, 'M': &re.Minutes, 'S': &re.Seconds}
+	count := 0
+	component := func(designators string, fields map[rune]*int) {
+		for designators != "" {
+			if digitCount(state) == 0 {
+				return
+			}
+			start := state.Pos()
+			v, err := strconv.Atoi(goP2.ToString(goP2.Many1(goP2.RuneOf(decimal)).Exec(state)))
+			if err != nil {
+				fail(state, start, "duration component out of range")
+			}
+			nsec := 0
+			if fields['S'] != nil {
+				nsec = fraction(state, ".,")
+			}
+			d := goP2.RuneOf(designators).Exec(state).(rune)
+			if nsec > 0 && d != 'S' {
+				fail(state, start, "only seconds may have a fraction")
+			}
+			*fields[d] = v
+			if d == 'S' {
+				re.Nanoseconds = nsec
+			}
+			count++
+			// 分量必须按照顺序出现，并且每个只能出现一次
+			for i, r := range designators {
+				if r == d {
+					designators = designators[i+1:]
+					break
+				}
+			}
+		}
+	}
+	component("YMWD", dateFields)
+	if _, ok := accept(state, "T"); ok {
+		before := count
+		component("HMS", timeFields)
+		if count == before {
+			fail(state, pos, "duration has no time component after T")
+		}
+	}
+	if count == 0 {
+		fail(state, pos, "duration has no components")
+	}
+	return re
+})
+
+// Duration 解析不含年和月的 ISO 8601 时长，结果为 time.Duration
+var Duration = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	p := ISOPeriod.Exec(state).(Period)
+	d, ok := p.Duration()
+	if !ok {
+		fail(state, pos, "years and months have no fixed duration")
+	}
+	return d
+})
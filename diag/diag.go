@@ -0,0 +1,282 @@
+// Package diag 将解析错误渲染为带有源码摘录的诊断信息，类似 rustc 和 clang 的格式：
+//
+//	error: Expect '='
+//	 --> input.txt:2:7
+//	  |
+//	1 | let a = 1
+//	2 | let x y
+//	  |       ^
+//
+// 位置都是以 rune 计的偏移，与 goP2.BasicStateFromText 构造的 State 的位置一致。
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Severity 是诊断的严重程度
+type Severity int
+
+const (
+	// SeverityError 是错误
+	SeverityError Severity = iota
+	// SeverityWarning 是警告
+	SeverityWarning
+	// SeverityNote 是提示
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	}
+	return "error"
+}
+
+// color 返回严重程度对应的 ANSI 颜色
+func (s Severity) color() string {
+	switch s {
+	case SeverityWarning:
+		return "\x1b[1;33m"
+	case SeverityNote:
+		return "\x1b[1;36m"
+	}
+	return "\x1b[1;31m"
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiGutter = "\x1b[1;34m"
+)
+
+// Diagnostic 是一条诊断信息， Start 和 End 是以 rune 计的偏移， End 不包含在内，
+// End 不大于 Start 时诊断只指向 Start 一个位置
+type Diagnostic struct {
+	Start    int
+	End      int
+	Severity Severity
+	// Code 是可选的错误代码
+	Code    string
+	Message string
+	// Expected 和 Unexpected 是出错位置上期望和实际遇到的内容
+	Expected   []string
+	Unexpected []string
+	// Related 是与诊断相关的其它位置
+	Related []Related
+}
+
+// Related 是与诊断相关的位置和说明
+type Related struct {
+	Start   int
+	End     int
+	Message string
+}
+
+// FromError 将 goP2.Error 转换为 Diagnostic ，其它的错误没有位置，转换失败
+func FromError(err error) (Diagnostic, bool) {
+	if e, ok := err.(goP2.Error); ok {
+		return Diagnostic{
+			Start:      e.Pos,
+			End:        e.End,
+			Severity:   SeverityError,
+			Code:       e.Code,
+			Message:    e.Message,
+			Expected:   e.Expected,
+			Unexpected: e.Unexpected,
+		}, true
+	}
+	return Diagnostic{}, false
+}
+
+// Renderer 渲染诊断信息
+type Renderer struct {
+	// Filename 显示在位置之前，为空时只显示行列
+	Filename string
+	// Color 为 true 时使用 ANSI 颜色
+	Color bool
+	// Context 是出错行前后显示的上下文行数
+	Context int
+}
+
+// source 是按行切分的源文本
+type source struct {
+	lines [][]rune
+	// starts[i] 是第 i 行开头的偏移
+	starts []int
+}
+
+func newSource(text string) *source {
+	s := &source{}
+	offset := 0
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSuffix(line, "\r"))
+		s.lines = append(s.lines, runes)
+		s.starts = append(s.starts, offset)
+		offset += len([]rune(line)) + 1
+	}
+	return s
+}
+
+// locate 返回偏移所在的行和列，都从 0 开始。超出文本的偏移落在最后一行的结尾
+func (s *source) locate(offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	line := sort.Search(len(s.starts), func(i int) bool { return s.starts[i] > offset }) - 1
+	col := offset - s.starts[line]
+	if col > len(s.lines[line]) {
+		col = len(s.lines[line])
+	}
+	return line, col
+}
+
+// wideRanges 是东亚宽字符（East Asian Wide 和 Fullwidth）的主要范围
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth 返回 rune 在终端中占的列数，宽字符占两列，其它占一列
+func runeWidth(r rune) int {
+	for _, w := range wideRanges {
+		if w[0] <= r && r <= w[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+func (r *Renderer) paint(color, text string) string {
+	if !r.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// Render 渲染 diags ，诊断按照位置排序，位置相同时保持原来的顺序
+func (r *Renderer) Render(text string, diags ...Diagnostic) string {
+	src := newSource(text)
+	var b strings.Builder
+	for i, d := range sortDiagnostics(diags) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		r.render(&b, src, d)
+	}
+	return b.String()
+}
+
+func (r *Renderer) render(b *strings.Builder, src *source, d Diagnostic) {
+	line, col := src.locate(d.Start)
+	endLine, endCol := line, col+1
+	if d.End > d.Start {
+		endLine, endCol = src.locate(d.End)
+	}
+	// 跨行的范围只标记第一行，至少标记一列
+	if endLine != line {
+		endCol = len(src.lines[line])
+	}
+	if endCol <= col {
+		endCol = col + 1
+	}
+
+	first, last := line-r.Context, line+r.Context
+	if first < 0 {
+		first = 0
+	}
+	if last >= len(src.lines) {
+		last = len(src.lines) - 1
+	}
+	// 文本结尾的换行之后的空行只在诊断指向它时显示
+	if last > line && len(src.lines[last]) == 0 {
+		last--
+	}
+	width := len(fmt.Sprint(last + 1))
+	gutter := func(label string) string {
+		return r.paint(ansiGutter, fmt.Sprintf("%*s |", width, label))
+	}
+
+	title := d.Severity.String()
+	if d.Code != "" {
+		title += "[" + d.Code + "]"
+	}
+	fmt.Fprintf(b, "%s%s\n", r.paint(d.Severity.color(), title), r.paint(ansiBold, ": "+d.Message))
+	where := fmt.Sprintf("%d:%d", line+1, col+1)
+	if r.Filename != "" {
+		where = r.Filename + ":" + where
+	}
+	fmt.Fprintf(b, "%s %s\n", r.paint(ansiGutter, strings.Repeat(" ", width)+"-->"), where)
+	fmt.Fprintf(b, "%s\n", gutter(""))
+	for i := first; i <= last; i++ {
+		fmt.Fprintf(b, "%s\n", strings.TrimRight(gutter(fmt.Sprint(i+1))+" "+string(src.lines[i]), " "))
+		if i != line {
+			continue
+		}
+		// 标记行保留源码行中的制表符，使得标记在展开制表符之后仍然对齐，宽字符占两列
+		var pad strings.Builder
+		for _, c := range src.lines[i][:col] {
+			if c == '\t' {
+				pad.WriteRune('\t')
+			} else {
+				pad.WriteString(strings.Repeat(" ", runeWidth(c)))
+			}
+		}
+		carets := endCol - col
+		if endCol <= len(src.lines[i]) {
+			carets = 0
+			for _, c := range src.lines[i][col:endCol] {
+				carets += runeWidth(c)
+			}
+		}
+		marker := r.paint(d.Severity.color(), strings.Repeat("^", carets))
+		fmt.Fprintf(b, "%s %s%s\n", gutter(""), pad.String(), marker)
+	}
+	for _, rel := range d.Related {
+		l, c := src.locate(rel.Start)
+		fmt.Fprintf(b, "%s %s: %d:%d: %s\n", r.paint(ansiGutter, strings.Repeat(" ", width)+" ="),
+			r.paint(ansiBold, "note"), l+1, c+1, rel.Message)
+	}
+}
+
+// Render 使用默认的 Renderer 渲染 errs 。可以转换为 Diagnostic 的错误带有源码摘录，其它的错误只显示信息
+func Render(text string, errs ...error) string {
+	r := &Renderer{}
+	diags := []Diagnostic{}
+	plain := []string{}
+	for _, err := range errs {
+		if d, ok := FromError(err); ok {
+			diags = append(diags, d)
+		} else {
+			plain = append(plain, "error: "+err.Error()+"\n")
+		}
+	}
+	re := r.Render(text, diags...)
+	for _, p := range plain {
+		if re != "" {
+			re += "\n"
+		}
+		re += p
+	}
+	return re
+}
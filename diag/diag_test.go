@@ -0,0 +1,80 @@
+package diag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func TestRenderError(t *testing.T) {
+	text := "let a = 1\nlet x y\n"
+	state := goP2.BasicStateFromText(text)
+	p := goP2.Str("let a = 1\nlet x ").Then(goP2.Fail("Expect '='"))
+	_, err := p.Parse(&state)
+	if err == nil {
+		t.Fatal("Expect error")
+	}
+	got := Render(text, err)
+	expect := "error: Expect '='\n" +
+		" --> 2:7\n" +
+		"  |\n" +
+		"2 | let x y\n" +
+		"  |       ^\n"
+	if got != expect {
+		t.Fatalf("Expect\n%s\nbut\n%s", expect, got)
+	}
+}
+
+func TestRenderMany(t *testing.T) {
+	text := "one\n\ttwo three\nfour\n"
+	r := &Renderer{Filename: "a.txt", Context: 1}
+	got := r.Render(text,
+		Diagnostic{Start: 16, End: 18, Severity: SeverityNote, Message: "last"},
+		Diagnostic{Start: 9, End: 14, Severity: SeverityWarning, Message: "word"},
+	)
+	expect := "warning: word\n" +
+		" --> a.txt:2:6\n" +
+		"  |\n" +
+		"1 | one\n" +
+		"2 | \ttwo three\n" +
+		"  | \t    ^^^^^\n" +
+		"3 | four\n" +
+		"\n" +
+		"note: last\n" +
+		" --> a.txt:3:2\n" +
+		"  |\n" +
+		"2 | \ttwo three\n" +
+		"3 | four\n" +
+		"  |  ^^\n"
+	if got != expect {
+		t.Fatalf("Expect\n%q\nbut\n%q", expect, got)
+	}
+
+	colored := (&Renderer{Color: true}).Render("x", Diagnostic{Start: 5, Message: "eof"})
+	if !strings.Contains(colored, "\x1b[1;31merror\x1b[0m") || !strings.Contains(colored, "1 |\x1b[0m x\n") {
+		t.Fatalf("unexpected colored output %q", colored)
+	}
+
+	if got := Render("x", errors.New("plain")); got != "error: plain\n" {
+		t.Fatalf("Expect plain message but %q", got)
+	}
+}
+
+func TestRenderWide(t *testing.T) {
+	got := Render("名字 = x!", goP2.Error{Pos: 6, Message: "unexpected '!'"})
+	expect := "error: unexpected '!'\n" +
+		" --> 1:7\n" +
+		"  |\n" +
+		"1 | 名字 = x!\n" +
+		"  |         ^\n"
+	if got != expect {
+		t.Fatalf("Expect\n%s\nbut\n%s", expect, got)
+	}
+
+	got = (&Renderer{}).Render("a 名字 b", Diagnostic{Start: 2, End: 4, Message: "name"})
+	if !strings.HasSuffix(got, "  |   ^^^^\n") {
+		t.Fatalf("Expect four carets under the wide runes but\n%s", got)
+	}
+}
@@ -0,0 +1,248 @@
+package diag
+
+import (
+	"encoding/json"
+	"sort"
+	"unicode/utf16"
+)
+
+// sortDiagnostics 返回按照位置排序的诊断，位置相同时保持原来的顺序
+func sortDiagnostics(diags []Diagnostic) []Diagnostic {
+	sorted := append([]Diagnostic{}, diags...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+// span 返回范围的开始和结尾，结尾不小于开始
+func span(start, end int) (int, int) {
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// Location 是导出的位置， Line 和 Column 从 1 开始， Column 以 rune 计
+type Location struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (s *source) location(offset int) Location {
+	line, col := s.locate(offset)
+	return Location{s.starts[line] + col, line + 1, col + 1}
+}
+
+type jsonRelated struct {
+	Start   Location `json:"start"`
+	End     Location `json:"end"`
+	Message string   `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	File       string        `json:"file,omitempty"`
+	Severity   string        `json:"severity"`
+	Code       string        `json:"code,omitempty"`
+	Message    string        `json:"message"`
+	Start      Location      `json:"start"`
+	End        Location      `json:"end"`
+	Expected   []string      `json:"expected,omitempty"`
+	Unexpected []string      `json:"unexpected,omitempty"`
+	Related    []jsonRelated `json:"related,omitempty"`
+}
+
+// JSON 将诊断导出为 JSON 数组，每一项包含严重程度、代码、信息、开始和结尾的位置、期望和相关位置。
+// filename 可以为空
+func JSON(filename, text string, diags ...Diagnostic) ([]byte, error) {
+	src := newSource(text)
+	re := []jsonDiagnostic{}
+	for _, d := range sortDiagnostics(diags) {
+		start, end := span(d.Start, d.End)
+		item := jsonDiagnostic{
+			File:       filename,
+			Severity:   d.Severity.String(),
+			Code:       d.Code,
+			Message:    d.Message,
+			Start:      src.location(start),
+			End:        src.location(end),
+			Expected:   d.Expected,
+			Unexpected: d.Unexpected,
+		}
+		for _, rel := range d.Related {
+			start, end := span(rel.Start, rel.End)
+			item.Related = append(item.Related, jsonRelated{src.location(start), src.location(end), rel.Message})
+		}
+		re = append(re, item)
+	}
+	return json.MarshalIndent(re, "", "  ")
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	CharOffset  int `json:"charOffset"`
+	CharLength  int `json:"charLength"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifLocation struct {
+	ID               *int          `json:"id,omitempty"`
+	PhysicalLocation sarifPhysical `json:"physicalLocation"`
+	Message          *sarifMessage `json:"message,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId,omitempty"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+func (s *source) sarifLocation(uri string, start, end int) sarifLocation {
+	start, end = span(start, end)
+	from, to := s.location(start), s.location(end)
+	return sarifLocation{PhysicalLocation: sarifPhysical{
+		ArtifactLocation: sarifArtifact{uri},
+		Region: sarifRegion{
+			StartLine:   from.Line,
+			StartColumn: from.Column,
+			EndLine:     to.Line,
+			EndColumn:   to.Column,
+			CharOffset:  from.Offset,
+			CharLength:  to.Offset - from.Offset,
+		},
+	}}
+}
+
+// SARIF 将诊断导出为只有一次运行的 SARIF 2.1.0 日志， tool 是工具的名字， uri 是被分析的文件。
+// 列以 Unicode 码点计（columnKind 为 unicodeCodePoints），错误代码作为 ruleId
+func SARIF(tool, uri, text string, diags ...Diagnostic) ([]byte, error) {
+	src := newSource(text)
+	results := []sarifResult{}
+	for _, d := range sortDiagnostics(diags) {
+		result := sarifResult{
+			RuleID:    d.Code,
+			Level:     d.Severity.String(),
+			Message:   sarifMessage{d.Message},
+			Locations: []sarifLocation{src.sarifLocation(uri, d.Start, d.End)},
+		}
+		for i, rel := range d.Related {
+			loc := src.sarifLocation(uri, rel.Start, rel.End)
+			id := i
+			loc.ID, loc.Message = &id, &sarifMessage{rel.Message}
+			result.RelatedLocations = append(result.RelatedLocations, loc)
+		}
+		results = append(results, result)
+	}
+	return json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:       sarifTool{sarifDriver{tool}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}, "", "  ")
+}
+
+// LSPPosition 是 LSP 的位置，行从 0 开始，列以 UTF-16 代码单元计
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// LSPRange 是 LSP 的范围
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+// LSPLocation 是 LSP 的文档位置
+type LSPLocation struct {
+	URI   string   `json:"uri"`
+	Range LSPRange `json:"range"`
+}
+
+// LSPRelatedInformation 是 LSP 诊断的相关信息
+type LSPRelatedInformation struct {
+	Location LSPLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+// LSPDiagnostic 是 LSP 的 Diagnostic 结构
+type LSPDiagnostic struct {
+	Range LSPRange `json:"range"`
+	// Severity 为 1 （Error）、 2 （Warning）或者 3 （Information）
+	Severity           int                     `json:"severity"`
+	Code               string                  `json:"code,omitempty"`
+	Source             string                  `json:"source,omitempty"`
+	Message            string                  `json:"message"`
+	RelatedInformation []LSPRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+func (s *source) lspPosition(offset int) LSPPosition {
+	line, col := s.locate(offset)
+	return LSPPosition{line, len(utf16.Encode(s.lines[line][:col]))}
+}
+
+func (s *source) lspRange(start, end int) LSPRange {
+	start, end = span(start, end)
+	return LSPRange{s.lspPosition(start), s.lspPosition(end)}
+}
+
+// LSP 将诊断转换为 LSP 的 Diagnostic 结构， uri 是文档的 URI ， source 填写在每一项的 source 中，可以为空
+func LSP(uri, source, text string, diags ...Diagnostic) []LSPDiagnostic {
+	src := newSource(text)
+	re := []LSPDiagnostic{}
+	for _, d := range sortDiagnostics(diags) {
+		item := LSPDiagnostic{
+			Range:    src.lspRange(d.Start, d.End),
+			Severity: int(d.Severity) + 1,
+			Code:     d.Code,
+			Source:   source,
+			Message:  d.Message,
+		}
+		for _, rel := range d.Related {
+			item.RelatedInformation = append(item.RelatedInformation, LSPRelatedInformation{
+				LSPLocation{uri, src.lspRange(rel.Start, rel.End)}, rel.Message,
+			})
+		}
+		re = append(re, item)
+	}
+	return re
+}
@@ -0,0 +1,139 @@
+package diag
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// sample 解析 "a = " 之后缺少值的输入，得到带有期望的错误
+func sample(t *testing.T) (string, Diagnostic) {
+	text := "名字 = \n  ?"
+	state := goP2.BasicStateFromText(text)
+	value := goP2.Choice(goP2.Label(goP2.Try(goP2.Many1(goP2.Digit)), "number"),
+		goP2.Label(goP2.Try(goP2.Chr('"')), "string"))
+	_, err := goP2.Str("名字 = \n  ").Then(value).Parse(&state)
+	d, ok := FromError(err)
+	if !ok {
+		t.Fatalf("Expect goP2.Error but %v", err)
+	}
+	if d.Code != "expect" {
+		t.Fatalf("Expect code expect but %q", d.Code)
+	}
+	d.Code = "E001"
+	d.Related = []Related{{Start: 0, End: 2, Message: "name defined here"}}
+	return text, d
+}
+
+func TestJSON(t *testing.T) {
+	text, d := sample(t)
+	data, err := JSON("a.conf", text, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]interface{}{{
+		"file":       "a.conf",
+		"severity":   "error",
+		"code":       "E001",
+		"message":    "Expect number or string but '?'",
+		"start":      map[string]interface{}{"offset": 8.0, "line": 2.0, "column": 3.0},
+		"end":        map[string]interface{}{"offset": 9.0, "line": 2.0, "column": 4.0},
+		"expected":   []interface{}{"number", "string"},
+		"unexpected": []interface{}{"'?'"},
+		"related": []interface{}{map[string]interface{}{
+			"start":   map[string]interface{}{"offset": 0.0, "line": 1.0, "column": 1.0},
+			"end":     map[string]interface{}{"offset": 2.0, "line": 1.0, "column": 3.0},
+			"message": "name defined here",
+		}},
+	}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Expect %v but %v", expect, got)
+	}
+
+	if got := Render(text, goP2.Error{Pos: 8, End: 9, Message: "m"}); !strings.Contains(got, "  |   ^\n") {
+		t.Fatalf("unexpected rendering %q", got)
+	}
+	rendered := (&Renderer{}).Render(text, d)
+	if !strings.HasPrefix(rendered, "error[E001]: ") || !strings.HasSuffix(rendered, "  = note: 1:1: name defined here\n") {
+		t.Fatalf("unexpected rendering %q", rendered)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	text, d := sample(t)
+	data, err := SARIF("conf-lint", "file:///a.conf", text, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool       struct{ Driver struct{ Name string } }
+			ColumnKind string
+			Results    []struct {
+				RuleID    string
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           sarifRegion
+					}
+				}
+				RelatedLocations []struct {
+					ID      int
+					Message struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	result := run.Results[0]
+	if log.Version != "2.1.0" || run.Tool.Driver.Name != "conf-lint" || run.ColumnKind != "unicodeCodePoints" {
+		t.Fatalf("unexpected run %s", data)
+	}
+	if result.RuleID != "E001" || result.Level != "error" || result.Message.Text != "Expect number or string but '?'" {
+		t.Fatalf("unexpected result %s", data)
+	}
+	loc := result.Locations[0].PhysicalLocation
+	expect := sarifRegion{StartLine: 2, StartColumn: 3, EndLine: 2, EndColumn: 4, CharOffset: 8, CharLength: 1}
+	if loc.ArtifactLocation.URI != "file:///a.conf" || loc.Region != expect {
+		t.Fatalf("Expect %v but %v", expect, loc.Region)
+	}
+	if len(result.RelatedLocations) != 1 || result.RelatedLocations[0].Message.Text != "name defined here" {
+		t.Fatalf("unexpected related locations %s", data)
+	}
+}
+
+func TestLSP(t *testing.T) {
+	text := "😀 x\ny"
+	got := LSP("file:///a", "goP2", text,
+		Diagnostic{Start: 4, End: 6, Severity: SeverityWarning, Message: "w"},
+		Diagnostic{Start: 2, Message: "e", Related: []Related{{Start: 0, End: 1, Message: "r"}}},
+	)
+	expect := []LSPDiagnostic{
+		{
+			Range:    LSPRange{LSPPosition{0, 3}, LSPPosition{0, 3}},
+			Severity: 1,
+			Source:   "goP2",
+			Message:  "e",
+			RelatedInformation: []LSPRelatedInformation{{
+				LSPLocation{"file:///a", LSPRange{LSPPosition{0, 0}, LSPPosition{0, 2}}}, "r",
+			}},
+		},
+		{Range: LSPRange{LSPPosition{1, 0}, LSPPosition{1, 1}}, Severity: 2, Source: "goP2", Message: "w"},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Expect %v but %v", expect, got)
+	}
+}
@@ -0,0 +1,126 @@
+// Package ebnf 读取 PEG 或者 ISO/IEC 14977 EBNF 格式的文法文本，将其转换为 grammar.Grammar ，
+// 并允许 Go 代码按规则名绑定语义动作，最终得到起始规则的 goP2.P 。
+//
+// 转换后的文法按照 PEG 的方式执行：选择是有序的，每个非最后的分支都用 Try 包装，
+// 可选项和重复项匹配失败时会回溯。字面量匹配的结果是 string ，字符集合匹配的结果是 rune ，
+// 序列和重复的结果是 []interface{} ，缺省的可选项结果是 nil 。
+package ebnf
+
+import (
+	"fmt"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+// Actions 将规则名映射到语义动作。动作的形式与 P.Bind 的 binder 相同，接收规则匹配的结果，
+// 返回后续的算子，通常是 goP2.Return 或者 goP2.Fail 。
+type Actions map[string]func(interface{}) goP2.P
+
+// Bind 将语义动作绑定到 g 中的同名规则上，规则不存在时返回错误
+func Bind(g *grammar.Grammar, actions Actions) error {
+	for name, action := range actions {
+		rule, ok := g.Rule(name)
+		if !ok {
+			return fmt.Errorf("ebnf: action for undefined rule %s", name)
+		}
+		g.Define(name, rule.Bind(action))
+	}
+	return nil
+}
+
+// LoadPEG 解析 PEG 文法，绑定语义动作并返回起始规则的算子，start 为空时使用第一条规则
+func LoadPEG(src, start string, actions Actions) (goP2.P, error) {
+	g, err := ParsePEG(src)
+	if err != nil {
+		return nil, err
+	}
+	return load(g, start, actions)
+}
+
+// LoadISO 解析 ISO EBNF 文法，绑定语义动作并返回起始规则的算子，start 为空时使用第一条规则。
+// 文法中的特殊序列 ? name ? 会被转换为对规则 name 的引用，需要先用 ParseISO 得到文法，
+// 在其中定义这些规则后再自行编译。
+func LoadISO(src, start string, actions Actions) (goP2.P, error) {
+	g, err := ParseISO(src)
+	if err != nil {
+		return nil, err
+	}
+	return load(g, start, actions)
+}
+
+func load(g *grammar.Grammar, start string, actions Actions) (goP2.P, error) {
+	if err := Bind(g, actions); err != nil {
+		return nil, err
+	}
+	if start == "" {
+		start = g.Names()[0]
+	}
+	return g.Compile(start)
+}
+
+type definition struct {
+	name string
+	node *grammar.Node
+}
+
+// parse 用给定的文法算子解析文本，得到的定义依次加入一个新的 Grammar
+func parse(p goP2.P, src string) (*grammar.Grammar, error) {
+	state := goP2.BasicStateFromText(src)
+	re, err := p.Parse(&state)
+	if err != nil {
+		if e, ok := err.(goP2.Error); ok {
+			at := goP2.LineCol([]rune(src), e.Pos)
+			return nil, fmt.Errorf("ebnf: line %d, column %d: %s", at.Line, at.Column, e.Message)
+		}
+		return nil, err
+	}
+	g := grammar.New()
+	for _, def := range re.([]interface{}) {
+		d := def.(definition)
+		if _, ok := g.Rule(d.name); ok {
+			return nil, fmt.Errorf("ebnf: rule %s is defined more than once", d.name)
+		}
+		g.Define(d.name, d.node)
+	}
+	return g, nil
+}
+
+// choice 按照 PEG 的有序选择构造结点，除最后一个分支外都用 Try 包装
+func choice(alternatives []interface{}) *grammar.Node {
+	if len(alternatives) == 1 {
+		return alternatives[0].(*grammar.Node)
+	}
+	nodes := make([]*grammar.Node, len(alternatives))
+	for i, alt := range alternatives {
+		node := alt.(*grammar.Node)
+		if i < len(alternatives)-1 {
+			node = grammar.Try(node)
+		}
+		nodes[i] = node
+	}
+	return grammar.Choice(nodes...)
+}
+
+// sequence 构造顺序结点，空序列匹配空输入
+func sequence(items []interface{}) *grammar.Node {
+	switch len(items) {
+	case 0:
+		return grammar.Return(nil)
+	case 1:
+		return items[0].(*grammar.Node)
+	}
+	nodes := make([]*grammar.Node, len(items))
+	for i, item := range items {
+		nodes[i] = item.(*grammar.Node)
+	}
+	return grammar.Seq(nodes...)
+}
+
+func optional(node *grammar.Node) *grammar.Node {
+	return grammar.Option(nil, grammar.Try(node))
+}
+
+func runes(input interface{}) string {
+	return goP2.ToString(input)
+}
@@ -0,0 +1,187 @@
+package ebnf
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+const calcPEG = `
+# 四则运算
+Expr    <- Term (AddOp Term)*
+Term    <- Factor (MulOp Factor)*
+Factor  <- Number / '(' Expr ')'
+AddOp   <- [+\-]
+MulOp   <- [*/]
+Number  <- [0-9]+
+`
+
+func fold(x interface{}) goP2.P {
+	items := x.([]interface{})
+	acc := items[0].(int)
+	for _, item := range items[1].([]interface{}) {
+		pair := item.([]interface{})
+		v := pair[1].(int)
+		switch pair[0].(rune) {
+		case '+':
+			acc += v
+		case '-':
+			acc -= v
+		case '*':
+			acc *= v
+		case '/':
+			acc /= v
+		}
+	}
+	return goP2.Return(acc)
+}
+
+var calcActions = Actions{
+	"Expr": fold,
+	"Term": fold,
+	"Factor": func(x interface{}) goP2.P {
+		if items, ok := x.([]interface{}); ok {
+			return goP2.Return(items[1])
+		}
+		return goP2.Return(x)
+	},
+	"Number": func(x interface{}) goP2.P {
+		n, err := strconv.Atoi(goP2.ToString(x))
+		if err != nil {
+			return goP2.Fail("%v", err)
+		}
+		return goP2.Return(n)
+	},
+}
+
+func TestLoadPEG(t *testing.T) {
+	p, err := LoadPEG(calcPEG, "", calcActions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := goP2.BasicStateFromText("2*(3+4)-10/5")
+	re, err := p.Over(goP2.EOF).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re != 12 {
+		t.Fatalf("Expect 12 but %v", re)
+	}
+}
+
+func TestParsePEGPrint(t *testing.T) {
+	g, err := ParsePEG(`Word <- !'end' [a-c]+ &' ' / "x\n"?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `Word ::= !"end" [abc]+ &" " | ("x" #xA)?` + "\n"
+	if re := g.EBNF(); re != expect {
+		t.Fatalf("Expect %s but %s", expect, re)
+	}
+}
+
+func TestParsePEGError(t *testing.T) {
+	_, err := ParsePEG("A <- 'a'\nB <- ('b'\n")
+	if err == nil {
+		t.Fatal("Expect a error for unclosed group")
+	}
+	if !strings.HasPrefix(err.Error(), "ebnf: line 2") {
+		t.Fatalf("Expect error on line 2 but %v", err)
+	}
+}
+
+const listISO = `
+(* 以逗号分隔的标识符列表 *)
+list = '[', [ident list], ']' ;
+ident list = ident, { ',', ident } ;
+ident = ? letter ?, { ? letter ? | digit } ;
+digit = '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9' ;
+`
+
+func TestParseISO(t *testing.T) {
+	g, err := ParseISO(listISO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := g.Names()
+	if len(names) != 4 || names[1] != "ident list" {
+		t.Fatalf("Expect 4 rules with \"ident list\" but %v", names)
+	}
+	g.Define("letter", grammar.RuneP("letter", unicode.IsLetter))
+	err = Bind(g, Actions{
+		"ident": func(x interface{}) goP2.P {
+			items := x.([]interface{})
+			var b strings.Builder
+			b.WriteRune(items[0].(rune))
+			for _, item := range items[1].([]interface{}) {
+				switch v := item.(type) {
+				case rune:
+					b.WriteRune(v)
+				case string:
+					b.WriteString(v)
+				}
+			}
+			return goP2.Return(b.String())
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := g.Compile("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := goP2.BasicStateFromText("[a1,bc,d]")
+	re, err := p.Over(goP2.EOF).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idents := re.([]interface{})[1].([]interface{})
+	if idents[0] != "a1" {
+		t.Fatalf("Expect a1 but %v", idents[0])
+	}
+}
+
+func TestParseISORepetitionLimit(t *testing.T) {
+	if _, err := ParseISO("a = 3 * 'x';"); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{"a = 99999999999 * 'x';", "a = 99999999999999999999 * 'x';"} {
+		_, err := ParseISO(src)
+		if err == nil || !strings.Contains(err.Error(), "repetition") {
+			t.Fatalf("%q: expect a repetition error but %v", src, err)
+		}
+	}
+
+	// 嵌套的重复按照展开之后的结点总数限制
+	g, err := ParseISO("a = 10 * (10 * (10 * 'x'));")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Compile("a"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseISO("a = 1000 * (1000 * (1000 * 'x'));")
+	if err == nil || !strings.Contains(err.Error(), "repetition") {
+		t.Fatalf("Expect a repetition error for nested repeats but %v", err)
+	}
+}
+
+func TestParseISOException(t *testing.T) {
+	p, err := LoadISO(`word = { letter } - 'end' ; letter = 'a' | 'd' | 'e' | 'n' ;`, "word", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := goP2.BasicStateFromText("end")
+	if _, err := p.Parse(&state); err == nil {
+		t.Fatal("Expect a error for excepted word end")
+	}
+	state = goP2.BasicStateFromText("and")
+	if _, err := p.Over(goP2.EOF).Parse(&state); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package ebnf
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+var isoComment = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Str("(*").Exec(state)
+	for {
+		if _, err := goP2.Try(goP2.Str("*)")).Parse(state); err == nil {
+			return nil
+		}
+		goP2.P(goP2.One).Exec(state)
+	}
+})
+
+var isoSpacing = goP2.Skip(goP2.Choice(goP2.Try(goP2.Space), isoComment))
+
+func isoToken(str string) goP2.P {
+	return goP2.Str(str).Over(isoSpacing)
+}
+
+var isoGap = goP2.Many1(goP2.RuneOf(" \t"))
+
+// isoIdentifier 解析元标识符，ISO EBNF 允许标识符内部出现空格，这里将其规范为单个空格
+var isoIdentifier = goP2.Do(func(state goP2.State) interface{} {
+	words := []string{isoWord.Exec(state).(string)}
+	for {
+		word, err := goP2.Try(isoGap.Then(isoWord)).Parse(state)
+		if err != nil {
+			break
+		}
+		words = append(words, word.(string))
+	}
+	return strings.Join(words, " ")
+}).Over(isoSpacing)
+
+var isoWord = goP2.Do(func(state goP2.State) interface{} {
+	head := goP2.P(goP2.Letter).Exec(state).(rune)
+	tail := goP2.Many(goP2.RuneP("identifier", func(r rune) bool {
+		return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	})).Exec(state)
+	return string([]rune{head}) + runes(tail)
+})
+
+func isoTerminal(q rune) goP2.P {
+	quote := string([]rune{q})
+	return goP2.Between(goP2.Chr(q), goP2.Chr(q), goP2.Many1(goP2.RuneNone(quote))).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(grammar.Str(runes(x)))
+	})
+}
+
+// isoSpecial 将 ? name ? 转换为对规则 name 的引用
+var isoSpecial = goP2.Between(goP2.Chr('?'), goP2.Chr('?'), goP2.Many(goP2.NChr('?'))).Bind(func(x interface{}) goP2.P {
+	return goP2.Return(grammar.Ref(strings.TrimSpace(runes(x))))
+})
+
+// isoDefinitions 在 init 中赋值，以打破包级变量之间的递归引用
+var isoDefinitions goP2.P
+
+func isoNested(state goP2.State) (interface{}, error) {
+	return isoDefinitions(state)
+}
+
+func isoBracket(open, close string, wrap func(*grammar.Node) *grammar.Node) goP2.P {
+	return goP2.Between(isoToken(open), isoToken(close), isoNested).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(wrap(x.(*grammar.Node)))
+	})
+}
+
+var isoPrimary = goP2.Choice(
+	goP2.Try(isoBracket("[", "]", optional)),
+	goP2.Try(isoBracket("{", "}", grammar.Many)),
+	goP2.Try(isoBracket("(", ")", func(node *grammar.Node) *grammar.Node { return node })),
+	goP2.Try(isoIdentifier).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(grammar.Ref(x.(string)))
+	}),
+	goP2.Try(isoTerminal('\'').Over(isoSpacing)),
+	goP2.Try(isoTerminal('"').Over(isoSpacing)),
+	goP2.Try(isoSpecial.Over(isoSpacing)),
+	goP2.Return(grammar.Return(nil)),
+)
+
+// maxRepetition 是 ISO EBNF 中 n * e 形式的重复次数上限，避免文法文本一次展开过多的结点。
+// 超出上限的错误用 Cut 标记，外层的括号分支不会回溯而掩盖它
+const maxRepetition = 1024
+
+// maxExpansion 是 n * e 展开之后的结点总数上限，嵌套的重复按照乘积计算
+const maxExpansion = 1 << 16
+
+// expandedSize 返回 node 展开之后的结点数，被共享的子结点每出现一次计算一次，超过 maxExpansion 之后不再精确
+func expandedSize(node *grammar.Node, memo map[*grammar.Node]int) int {
+	if n, ok := memo[node]; ok {
+		return n
+	}
+	n := 1
+	for _, child := range node.Children {
+		if n += expandedSize(child, memo); n > maxExpansion {
+			n = maxExpansion + 1
+			break
+		}
+	}
+	memo[node] = n
+	return n
+}
+
+var isoFactor = goP2.Do(func(state goP2.State) interface{} {
+	count, err := goP2.Try(goP2.P(goP2.UInt).Over(isoSpacing).Over(isoToken("*"))).Parse(state)
+	node := isoPrimary.Exec(state).(*grammar.Node)
+	if err != nil {
+		return node
+	}
+	n, e := strconv.Atoi(count.(string))
+	if e != nil {
+		goP2.Cut(goP2.Fail("invalid repetition %s", count)).Exec(state)
+	}
+	if n > maxRepetition {
+		goP2.Cut(goP2.Fail("repetition %d exceeds the limit %d", n, maxRepetition)).Exec(state)
+	}
+	if size := expandedSize(node, map[*grammar.Node]int{}); n*size > maxExpansion {
+		goP2.Cut(goP2.Fail("repetition %d of %d nodes exceeds the limit of %d nodes", n, size, maxExpansion)).Exec(state)
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = node
+	}
+	return sequence(items)
+})
+
+var isoTerm = goP2.Do(func(state goP2.State) interface{} {
+	node := isoFactor.Exec(state).(*grammar.Node)
+	exception, err := goP2.Try(isoToken("-").Then(isoFactor)).Parse(state)
+	if err != nil {
+		return node
+	}
+	return grammar.Not(exception.(*grammar.Node)).Then(node)
+})
+
+var isoSingle = goP2.SepBy1(isoTerm, isoToken(",")).Bind(func(x interface{}) goP2.P {
+	return goP2.Return(sequence(x.([]interface{})))
+})
+
+var isoRule = goP2.Do(func(state goP2.State) interface{} {
+	name := isoIdentifier.Exec(state).(string)
+	isoToken("=").Exec(state)
+	node := isoDefinitions.Exec(state).(*grammar.Node)
+	goP2.RuneOf(";.").Over(isoSpacing).Exec(state)
+	return definition{name, node}
+})
+
+var isoGrammar = isoSpacing.Then(goP2.Many1(isoRule)).Over(goP2.EOF)
+
+func init() {
+	isoDefinitions = goP2.SepBy1(isoSingle, isoToken("|")).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(choice(x.([]interface{})))
+	})
+}
+
+// ParseISO 解析 ISO/IEC 14977 EBNF 格式的文法文本。支持 name = defs ; 形式的规则，
+// | 分隔的选择， , 分隔的连接， [ ] 可选， { } 重复， ( ) 分组， n * e 定长重复，
+// a - b 例外，单双引号终结符，空定义，(* *) 注释以及 ? name ? 特殊序列。
+// 元标识符内部可以包含空格，它们会被规范为单个空格。
+func ParseISO(src string) (*grammar.Grammar, error) {
+	return parse(isoGrammar, src)
+}
@@ -0,0 +1,190 @@
+package ebnf
+
+import (
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+// 字符集合中的区间超过这个大小时不再展开为 RuneOf ，而是用谓词判断
+const maxClassExpansion = 256
+
+var pegComment = goP2.Chr('#').Then(goP2.Skip(goP2.NChr('\n')))
+
+var pegSpacing = goP2.Skip(goP2.Choice(goP2.Try(goP2.Space), pegComment))
+
+func pegToken(str string) goP2.P {
+	return goP2.Str(str).Over(pegSpacing)
+}
+
+var identStart = goP2.RuneP("identifier", func(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+})
+
+var identRest = goP2.RuneP("identifier", func(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+})
+
+var identWord = goP2.Do(func(state goP2.State) interface{} {
+	head := identStart.Exec(state).(rune)
+	tail := goP2.Many(identRest).Exec(state)
+	return string([]rune{head}) + runes(tail)
+})
+
+var pegIdentifier = identWord.Over(pegSpacing)
+
+// pegEscape 解析反斜杠之后的转义字符
+var pegEscape = goP2.P(goP2.One).Bind(func(x interface{}) goP2.P {
+	switch x.(rune) {
+	case 'n':
+		return goP2.Return('\n')
+	case 'r':
+		return goP2.Return('\r')
+	case 't':
+		return goP2.Return('\t')
+	}
+	return goP2.Return(x)
+})
+
+func pegChar(stop string) goP2.P {
+	return goP2.Choice(goP2.Try(goP2.Chr('\\').Then(pegEscape)), goP2.RuneNone(stop+"\\"))
+}
+
+func pegLiteral(q rune) goP2.P {
+	quote := string([]rune{q})
+	return goP2.Between(goP2.Chr(q), goP2.Chr(q), goP2.Many(pegChar(quote))).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(grammar.Str(runes(x)))
+	})
+}
+
+type classRange struct {
+	from, to rune
+}
+
+var pegClassItem = goP2.Do(func(state goP2.State) interface{} {
+	from := pegChar("]").Exec(state).(rune)
+	to, err := goP2.Try(goP2.Chr('-').Then(pegChar("]"))).Parse(state)
+	if err != nil {
+		return classRange{from, from}
+	}
+	return classRange{from, to.(rune)}
+})
+
+var pegClass = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Chr('[').Exec(state)
+	_, err := goP2.Try(goP2.Chr('^')).Parse(state)
+	negate := err == nil
+	items := goP2.Many(pegClassItem).Exec(state).([]interface{})
+	goP2.Chr(']').Exec(state)
+	ranges := make([]classRange, len(items))
+	for i, item := range items {
+		ranges[i] = item.(classRange)
+	}
+	return class(ranges, negate)
+})
+
+// class 将字符区间转换为结点，较小的集合展开为 RuneOf 或 RuneNone ，以便打印和计算 FIRST 集
+func class(ranges []classRange, negate bool) *grammar.Node {
+	var data []rune
+	var source []rune
+	for _, r := range ranges {
+		if len(data) <= maxClassExpansion {
+			for c := r.from; c <= r.to && len(data) <= maxClassExpansion; c++ {
+				data = append(data, c)
+			}
+		}
+		source = append(source, r.from)
+		if r.to != r.from {
+			source = append(source, '-', r.to)
+		}
+	}
+	if len(data) <= maxClassExpansion {
+		if negate {
+			return grammar.RuneNone(string(data))
+		}
+		return grammar.RuneOf(string(data))
+	}
+	name := "[" + string(source) + "]"
+	if negate {
+		name = "[^" + string(source) + "]"
+	}
+	return grammar.RuneP(name, func(c rune) bool {
+		for _, r := range ranges {
+			if r.from <= c && c <= r.to {
+				return !negate
+			}
+		}
+		return negate
+	})
+}
+
+// pegExpression 在 init 中赋值，以打破包级变量之间的递归引用
+var pegExpression goP2.P
+
+func pegNested(state goP2.State) (interface{}, error) {
+	return pegExpression(state)
+}
+
+var pegPrimary = goP2.Choice(
+	goP2.Try(pegIdentifier.Over(goP2.FailIf(goP2.Str("<-")))).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(grammar.Ref(x.(string)))
+	}),
+	goP2.Try(goP2.Between(pegToken("("), pegToken(")"), pegNested)),
+	goP2.Try(pegLiteral('\'').Over(pegSpacing)),
+	goP2.Try(pegLiteral('"').Over(pegSpacing)),
+	goP2.Try(pegClass.Over(pegSpacing)),
+	pegToken(".").Then(goP2.Return(grammar.One())),
+)
+
+var pegSuffix = goP2.Do(func(state goP2.State) interface{} {
+	node := pegPrimary.Exec(state).(*grammar.Node)
+	suffix := goP2.Option("", goP2.Try(goP2.RuneOf("?*+").Over(pegSpacing))).Exec(state)
+	switch suffix {
+	case '?':
+		return optional(node)
+	case '*':
+		return grammar.Many(node)
+	case '+':
+		return grammar.Many1(node)
+	}
+	return node
+})
+
+var pegPrefix = goP2.Do(func(state goP2.State) interface{} {
+	prefix := goP2.Option("", goP2.Try(goP2.RuneOf("&!").Over(pegSpacing))).Exec(state)
+	node := pegSuffix.Exec(state).(*grammar.Node)
+	switch prefix {
+	case '&':
+		return grammar.Ahead(node)
+	case '!':
+		return grammar.Not(node)
+	}
+	return node
+})
+
+var pegSequence = goP2.Many(pegPrefix).Bind(func(x interface{}) goP2.P {
+	return goP2.Return(sequence(x.([]interface{})))
+})
+
+var pegDefinition = goP2.Do(func(state goP2.State) interface{} {
+	name := pegIdentifier.Exec(state).(string)
+	pegToken("<-").Exec(state)
+	node := pegExpression.Exec(state).(*grammar.Node)
+	return definition{name, node}
+})
+
+var pegGrammar = pegSpacing.Then(goP2.Many1(pegDefinition)).Over(goP2.EOF)
+
+func init() {
+	pegExpression = goP2.SepBy1(pegSequence, pegToken("/")).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(choice(x.([]interface{})))
+	})
+}
+
+// ParsePEG 解析 PEG 格式的文法文本。支持的语法与 Ford 的 PEG 论文一致：
+// name <- expr 定义规则，/ 是有序选择，支持 &e 、 !e 、 e? 、 e* 、 e+ 、括号分组、
+// 单双引号字面量、 [a-z] 与 [^...] 字符集合、 . 以及 # 开头的注释。
+func ParsePEG(src string) (*grammar.Grammar, error) {
+	return parse(pegGrammar, src)
+}
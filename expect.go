@@ -0,0 +1,95 @@
+package goP2
+
+import "fmt"
+
+// describeElement 描述一个输入元素，用于错误信息， rune 以引号包围
+func describeElement(x interface{}) string {
+	if r, ok := x.(rune); ok {
+		return fmt.Sprintf("%q", r)
+	}
+	return fmt.Sprint(x)
+}
+
+// endOfInput 是到达结尾时的 Unexpected
+const endOfInput = "end of input"
+
+// expectCode 返回期望错误的错误代码和参数
+func expectCode(expected, unexpected []string) (string, []interface{}) {
+	if len(unexpected) == 1 && unexpected[0] == endOfInput {
+		return "expect.eof", []interface{}{expected}
+	}
+	return "expect", []interface{}{expected, unexpected}
+}
+
+// appendNew 将 items 中还没有出现过的项追加到 set 之后
+func appendNew(set []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, x := range set {
+			if x == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			set = append(set, item)
+		}
+	}
+	return set
+}
+
+// mergeExpected 将 Choice 各个分支的期望合并到最后一个分支的错误中。只合并与最后的错误位置相同的分支，
+// 回溯之前在别处失败的分支描述的是另一个位置。只有最后的错误也带有期望，并且合并之后的期望更多时才重新构造错误信息
+func mergeExpected(err error, failures []Error) error {
+	e, ok := err.(Error)
+	if !ok || len(e.Expected) == 0 {
+		return err
+	}
+	var expected, unexpected []string
+	for _, f := range failures {
+		if f.Pos == e.Pos {
+			expected = appendNew(expected, f.Expected...)
+			unexpected = appendNew(unexpected, f.Unexpected...)
+		}
+	}
+	if len(expected) <= len(e.Expected) {
+		return err
+	}
+	e.Expected, e.Unexpected = expected, unexpected
+	e.Code, e.Args = expectCode(expected, unexpected)
+	e.Message, _ = English.format(e.Code, e.Args)
+	return e
+}
+
+// Unexpected 构造当前位置上期望 expected 的错误，不消费输入。错误的 Unexpected 是当前位置的元素，
+// 范围覆盖这个元素，到达结尾时是 "end of input"
+func Unexpected(state State, expected ...string) error {
+	pos := state.Pos()
+	found, end := endOfInput, pos
+	if x, err := state.Next(); err == nil {
+		found, end = describeElement(x), state.Pos()
+	}
+	restore(state, pos)
+	unexpected := []string{found}
+	code, args := expectCode(expected, unexpected)
+	err := Raise(state, code, args...)
+	if e, ok := err.(Error); ok {
+		e.End, e.Expected, e.Unexpected = end, expected, unexpected
+		return e
+	}
+	return err
+}
+
+// Label 在 p 没有消费输入而失败时，将错误替换为期望 name 的错误，参见 Unexpected 。
+// Choice 会合并各个分支的期望，所以 Choice(Label(a, "number"), Label(b, "string")) 的错误是
+// "Expect number or string but ..."
+func Label(p P, name string) P {
+	return func(state State) (interface{}, error) {
+		pos := state.Pos()
+		re, err := p(state)
+		if err == nil || state.Pos() != pos || IsCut(err) {
+			return re, err
+		}
+		return nil, Unexpected(state, name)
+	}
+}
@@ -0,0 +1,49 @@
+package goP2
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	number := Label(Try(Many1(Digit)), "number")
+	str := Label(Try(Chr('"')), "string")
+	value := Choice(number, str, Label(Try(Str("null")), "null"))
+
+	state := BasicStateFromText("x1")
+	_, err := value.Parse(&state)
+	e, ok := err.(Error)
+	if !ok {
+		t.Fatalf("Expect Error but %v", err)
+	}
+	expect := Error{Pos: 0, End: 1, Message: "Expect number, string or null but 'x'",
+		Expected: []string{"number", "string", "null"}, Unexpected: []string{"'x'"},
+		Code: "expect", Args: []interface{}{[]string{"number", "string", "null"}, []string{"'x'"}}}
+	if !reflect.DeepEqual(e, expect) {
+		t.Fatalf("Expect %#v but %#v", expect, e)
+	}
+	if state.Pos() != 0 {
+		t.Fatalf("Expect position 0 but %d", state.Pos())
+	}
+
+	state = BasicStateFromText("")
+	_, err = number.Parse(&state)
+	if e := err.(Error); e.Message != "Expect number but end of input" || e.End != 0 {
+		t.Fatalf("unexpected error %#v", e)
+	}
+
+	// 消费了输入之后的错误不被替换
+	state = BasicStateFromText("nul")
+	_, err = Label(Str("null"), "null").Parse(&state)
+	if e := err.(Error); len(e.Expected) != 0 {
+		t.Fatalf("Expect the original error but %#v", e)
+	}
+
+	// 在别处失败并且回溯的分支不参与合并
+	p := Choice(Try(Str("ab").Then(Label(Try(Chr('x')), "x"))), Label(Try(Chr('z')), "z"))
+	state = BasicStateFromText("abq")
+	_, err = p.Parse(&state)
+	if e := err.(Error); e.Message != "Expect z but 'a'" || e.Pos != 0 {
+		t.Fatalf("Expect only the branch at 0 but %#v", e)
+	}
+}
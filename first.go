@@ -0,0 +1,279 @@
+package goP2
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FirstSet 描述算子匹配成功时可能读到的第一个元素。Known 为 false 表示无法确定，
+// Nullable 表示算子可能不消费任何元素就成功。
+type FirstSet struct {
+	Known    bool
+	Nullable bool
+	Elements map[interface{}]bool
+}
+
+// UnknownFirst 是无法确定的 FIRST 集，使用它的分支总是会被尝试
+var UnknownFirst = FirstSet{}
+
+// EmptyFirst 是不消费任何元素的算子（例如 Return）的 FIRST 集
+var EmptyFirst = FirstSet{Known: true, Nullable: true}
+
+// FirstOf 用给定的元素构造 FIRST 集，元素的类型要与 State 迭代出的类型一致，
+// 例如文本状态中是 rune ，字节状态中是 byte
+func FirstOf(elements ...interface{}) FirstSet {
+	set := FirstSet{Known: true, Elements: make(map[interface{}]bool, len(elements))}
+	for _, element := range elements {
+		set.Elements[element] = true
+	}
+	return set
+}
+
+// Accept 判断 x 是否可能作为首元素被接受，未知或者可空的集合总是返回 true
+func (s FirstSet) Accept(x interface{}) bool {
+	if !s.Known || s.Nullable {
+		return true
+	}
+	return isComparable(x) && s.Elements[x]
+}
+
+// Union 合并两个 FIRST 集，对应选择分支
+func (s FirstSet) Union(o FirstSet) FirstSet {
+	if !s.Known || !o.Known {
+		return UnknownFirst
+	}
+	re := FirstOf()
+	for element := range s.Elements {
+		re.Elements[element] = true
+	}
+	for element := range o.Elements {
+		re.Elements[element] = true
+	}
+	re.Nullable = s.Nullable || o.Nullable
+	return re
+}
+
+// Then 计算 s 之后顺序连接 o 得到的 FIRST 集
+func (s FirstSet) Then(o FirstSet) FirstSet {
+	if !s.Known {
+		return UnknownFirst
+	}
+	if !s.Nullable {
+		return s
+	}
+	re := s.Union(o)
+	re.Nullable = re.Known && o.Nullable
+	return re
+}
+
+func (s FirstSet) String() string {
+	if !s.Known {
+		return "unknown"
+	}
+	items := s.describe()
+	if s.Nullable {
+		items = append(items, "ε")
+	}
+	return "[" + strings.Join(items, " ") + "]"
+}
+
+// describe 返回排好序的元素描述，用于错误信息
+func (s FirstSet) describe() []string {
+	items := make([]string, 0, len(s.Elements)+1)
+	for element := range s.Elements {
+		items = append(items, describeElement(element))
+	}
+	sort.Strings(items)
+	return items
+}
+
+func isComparable(x interface{}) bool {
+	switch x.(type) {
+	case rune, byte, string, int:
+		return true
+	case nil:
+		return true
+	}
+	return reflect.TypeOf(x).Comparable()
+}
+
+// F 是携带 FIRST 集的算子，供 Dispatch 这样的优化使用
+type F struct {
+	P     P
+	First FirstSet
+}
+
+// WithFirst 为一个算子声明 FIRST 集
+func WithFirst(p P, first FirstSet) F {
+	return F{p, first}
+}
+
+// Parse 调用被封装的算子
+func (f F) Parse(state State) (interface{}, error) {
+	return f.P(state)
+}
+
+// Then 对应 P 的 Then ，同时推导 FIRST 集
+func (f F) Then(g F) F {
+	return F{f.P.Then(g.P), f.First.Then(g.First)}
+}
+
+// Over 对应 P 的 Over ，同时推导 FIRST 集
+func (f F) Over(g F) F {
+	return F{f.P.Over(g.P), f.First.Then(g.First)}
+}
+
+// Bind 对应 P 的 Bind 。binder 生成的算子无法预知，所以只有 f 不可空时 FIRST 集才是已知的
+func (f F) Bind(binder func(interface{}) P) F {
+	first := f.First
+	if first.Nullable {
+		first = UnknownFirst
+	}
+	return F{f.P.Bind(binder), first}
+}
+
+// FChr 是带 FIRST 集的 Chr
+func FChr(val rune) F {
+	return F{Chr(val), FirstOf(val)}
+}
+
+// FRuneOf 是带 FIRST 集的 RuneOf
+func FRuneOf(str string) F {
+	data := []rune(str)
+	elements := make([]interface{}, 0, len(data))
+	for _, r := range data {
+		elements = append(elements, r)
+	}
+	return F{RuneOf(str), FirstOf(elements...)}
+}
+
+// FStr 是带 FIRST 集的 Str
+func FStr(str string) F {
+	data := []rune(str)
+	if len(data) == 0 {
+		return F{Str(str), EmptyFirst}
+	}
+	return F{Str(str), FirstOf(data[0])}
+}
+
+// FByte 是带 FIRST 集的 Byte
+func FByte(val byte) F {
+	return F{Byte(val), FirstOf(val)}
+}
+
+// FByteOf 是带 FIRST 集的 ByteOf
+func FByteOf(str string) F {
+	data := []byte(str)
+	elements := make([]interface{}, 0, len(data))
+	for _, b := range data {
+		elements = append(elements, b)
+	}
+	return F{ByteOf(str), FirstOf(elements...)}
+}
+
+// FBytes 是带 FIRST 集的 Bytes
+func FBytes(str string) F {
+	if len(str) == 0 {
+		return F{Bytes(str), EmptyFirst}
+	}
+	return F{Bytes(str), FirstOf(str[0])}
+}
+
+// FEq 是带 FIRST 集的 Eq ，不可比较的值无法放入 FIRST 集
+func FEq(val interface{}) F {
+	if !isComparable(val) {
+		return F{Eq(val), UnknownFirst}
+	}
+	return F{Eq(val), FirstOf(val)}
+}
+
+// FReturn 是带 FIRST 集的 Return
+func FReturn(val interface{}) F {
+	return F{Return(val), EmptyFirst}
+}
+
+// FTry 是带 FIRST 集的 Try
+func FTry(f F) F {
+	return F{Try(f.P), f.First}
+}
+
+// FMany 是带 FIRST 集的 Many
+func FMany(f F) F {
+	first := f.First
+	if first.Known {
+		first = first.Union(EmptyFirst)
+	}
+	return F{Many(f.P), first}
+}
+
+// FMany1 是带 FIRST 集的 Many1
+func FMany1(f F) F {
+	return F{Many1(f.P), f.First}
+}
+
+// Dispatch 是 Choice 的编译形式。它根据各分支的 FIRST 集构造一张由下一个元素到候选分支的
+// 调度表，只尝试可能接受该元素的分支；FIRST 集未知或者可空的分支总是会被尝试。候选分支
+// 之间仍然按照 Choice 的规则依次尝试。没有分支接受下一个元素时，错误与 Label 相同，期望 FIRST 集中的元素。
+//
+// 注意 Dispatch 只在各分支失败时都不消费输入（例如用 Try 包装）时才与 Choice 接受相同的输入。
+// 不接受下一个元素的分支不会运行，所以它们消费输入之后失败也不会阻止后面的分支：
+// Choice(Chr('a'), Chr('b')) 在 "b" 上失败，而对应的 Dispatch 会成功。
+func Dispatch(fs ...F) F {
+	first := EmptyFirst
+	if len(fs) > 0 {
+		first = fs[0].First
+		for _, f := range fs[1:] {
+			first = first.Union(f.First)
+		}
+	}
+
+	choice := func(accept func(FirstSet) bool) P {
+		var candidates []P
+		for _, f := range fs {
+			if accept(f.First) {
+				candidates = append(candidates, f.P)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			return nil
+		case 1:
+			return candidates[0]
+		}
+		return Choice(candidates...)
+	}
+
+	table := make(map[interface{}]P)
+	for _, f := range fs {
+		for element := range f.First.Elements {
+			if _, ok := table[element]; !ok {
+				x := element
+				table[x] = choice(func(s FirstSet) bool { return s.Accept(x) })
+			}
+		}
+	}
+	fallback := choice(func(s FirstSet) bool { return !s.Known || s.Nullable })
+
+	p := func(state State) (interface{}, error) {
+		pos := state.Pos()
+		x, err := state.Next()
+		psc := fallback
+		if err == nil {
+			state.SeekTo(pos)
+			if isComparable(x) {
+				if c, ok := table[x]; ok {
+					psc = c
+				}
+			}
+		}
+		if psc == nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, Unexpected(state, first.describe()...)
+		}
+		return psc(state)
+	}
+	return F{p, first}
+}
@@ -0,0 +1,82 @@
+package goP2
+
+import "testing"
+
+func TestFirstSetThen(t *testing.T) {
+	f := FMany(FChr('-')).Then(FRuneOf("0123456789"))
+	for _, r := range "-0123456789" {
+		if !f.First.Accept(r) {
+			t.Fatalf("Expect %q in first set %v", r, f.First)
+		}
+	}
+	if f.First.Accept('a') {
+		t.Fatalf("Expect 'a' not in first set %v", f.First)
+	}
+	if f.First.Nullable {
+		t.Fatalf("Expect first set %v not nullable", f.First)
+	}
+}
+
+func TestFirstSetBind(t *testing.T) {
+	f := FMany(FChr('a')).Bind(func(x interface{}) P {
+		return Return(x)
+	})
+	if f.First.Known {
+		t.Fatalf("Expect unknown first set but %v", f.First)
+	}
+}
+
+var keywords = Dispatch(
+	FTry(FStr("let")),
+	FTry(FStr("lambda")),
+	FStr("if"),
+	WithFirst(Digit, UnknownFirst),
+)
+
+func TestDispatch0(t *testing.T) {
+	for _, data := range []string{"let", "lambda", "if"} {
+		state := BasicStateFromText(data)
+		re, err := keywords.Over(WithFirst(EOF, EmptyFirst)).Parse(&state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if re != data {
+			t.Fatalf("Expect %s but %v", data, re)
+		}
+	}
+}
+
+func TestDispatch1(t *testing.T) {
+	state := BasicStateFromText("7")
+	re, err := keywords.Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re != '7' {
+		t.Fatalf("Expect '7' but %v", re)
+	}
+}
+
+func TestDispatch2(t *testing.T) {
+	ops := Dispatch(FChr('+'), FChr('-'), FChr('*'))
+	state := BasicStateFromText("/")
+	re, err := ops.Parse(&state)
+	if err == nil {
+		t.Fatalf("Expect a error when data / but got %v.", re)
+	}
+	if state.Pos() != 0 {
+		t.Fatalf("Expect dispatch failed at 0 but %d", state.Pos())
+	}
+}
+
+func TestDispatchSkipsConsumingBranches(t *testing.T) {
+	// Choice 在第一个分支消费了 'b' 之后失败，Dispatch 直接选择第二个分支
+	state := BasicStateFromText("b")
+	if _, err := Choice(Chr('a'), Chr('b')).Parse(&state); err == nil {
+		t.Fatalf("Expect Choice to fail after the first branch consumed input")
+	}
+	state = BasicStateFromText("b")
+	if re, err := Dispatch(FChr('a'), FChr('b')).Parse(&state); err != nil || re != 'b' {
+		t.Fatalf("Expect Dispatch to choose 'b' but %v, %v", re, err)
+	}
+}
@@ -0,0 +1,128 @@
+package goP2
+
+// window 将 State 限制在 end 之前，用于解析定长的数据块
+type window struct {
+	State
+	end int
+}
+
+// Unwrap 返回被限制的 State
+func (w *window) Unwrap() State {
+	return w.State
+}
+
+// Next 在到达窗口结尾时返回 eof 错误
+func (w *window) Next() (interface{}, error) {
+	if w.Pos() >= w.end {
+		return nil, Raise(w, "eof")
+	}
+	return w.State.Next()
+}
+
+// SeekTo 不允许移动到窗口之外
+func (w *window) SeekTo(pos int) bool {
+	if pos > w.end {
+		return false
+	}
+	return w.State.SeekTo(pos)
+}
+
+// Sized 将 p 限制在后续的 n 个元素之内，p 必须恰好消费这 n 个元素
+func Sized(n int, p P) P {
+	return func(state State) (interface{}, error) {
+		start := state.Pos()
+		re, err := p(&window{state, start + n})
+		if err != nil {
+			return nil, err
+		}
+		if used := state.Pos() - start; used != n {
+			return nil, Raise(state, "sized", n, used)
+		}
+		return re, nil
+	}
+}
+
+// toLength 将长度算子的结果转换为 int
+func toLength(x interface{}) (int, bool) {
+	var n int64
+	switch v := x.(type) {
+	case int:
+		n = int64(v)
+	case int8:
+		n = int64(v)
+	case int16:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case uint8:
+		n = int64(v)
+	case uint16:
+		n = int64(v)
+	case uint32:
+		n = int64(v)
+	case uint64:
+		if v > uint64(int(^uint(0)>>1)) {
+			return 0, false
+		}
+		n = int64(v)
+	default:
+		return 0, false
+	}
+	if n < 0 || n > int64(int(^uint(0)>>1)) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// LengthPrefixed 先用 lenP 读取长度，再用 bodyP 解析恰好这么长的数据。
+// lenP 的结果可以是任意的整数类型，例如 Uint8 、 Uint16BE 或者 Uvarint
+func LengthPrefixed(lenP, bodyP P) P {
+	return func(state State) (interface{}, error) {
+		x, err := lenP(state)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := toLength(x)
+		if !ok {
+			return nil, Raise(state, "length", x)
+		}
+		return Sized(n, bodyP)(state)
+	}
+}
+
+// TLVRecord 是 TLV 算子的结果
+type TLVRecord struct {
+	Tag    interface{}
+	Length int
+	Value  interface{}
+}
+
+// TLV 依次读取标签、长度和值，用 dispatch 按照标签选择解析值的算子，值的算子被限制在长度之内。
+// dispatch 返回 nil 时值保留为原始的 []byte 。结果为 TLVRecord
+func TLV(tagP, lenP P, dispatch func(tag interface{}) P) P {
+	return func(state State) (interface{}, error) {
+		tag, err := tagP(state)
+		if err != nil {
+			return nil, err
+		}
+		x, err := lenP(state)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := toLength(x)
+		if !ok {
+			return nil, Raise(state, "length", x)
+		}
+		body := dispatch(tag)
+		if body == nil {
+			body = Take(n)
+		}
+		value, err := Sized(n, body)(state)
+		if err != nil {
+			return nil, err
+		}
+		return TLVRecord{tag, n, value}, nil
+	}
+}
@@ -0,0 +1,75 @@
+package goP2
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSized(t *testing.T) {
+	state := BasicStateFromBytes([]byte("abcdef"))
+	re, err := Sized(3, Many(One)).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(re.([]interface{})) != 3 || state.Pos() != 3 {
+		t.Fatalf("Expect 3 bytes inside the window but %v at %d", re, state.Pos())
+	}
+	state = BasicStateFromBytes([]byte("abcdef"))
+	if _, err := Sized(3, Take(2)).Parse(&state); err == nil {
+		t.Fatalf("Expect error when the window is not consumed")
+	}
+	state = BasicStateFromBytes([]byte("abcdef"))
+	if _, err := Sized(2, Take(3)).Parse(&state); err == nil {
+		t.Fatalf("Expect error when reading past the window")
+	}
+}
+
+func TestLengthPrefixed(t *testing.T) {
+	// 两个长度前缀的字符串，之后是一个大端序的 uint16
+	data := []byte("\x02hi\x00\x00\x05hello\x01\x02")
+	state := BasicStateFromBytes(data)
+	str := LengthPrefixed(Uint8, Many(One))
+	re, err := str.Then(Uint8).Then(LengthPrefixed(Uint16BE, Take(5))).Over(Uint16BE).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(re, []byte("hello")) {
+		t.Fatalf("Expect hello but %v", re)
+	}
+	state = BasicStateFromBytes([]byte("\x05abc"))
+	if _, err := LengthPrefixed(Uint8, Many(One)).Parse(&state); err == nil {
+		t.Fatalf("Expect error for truncated body")
+	}
+}
+
+func TestTLV(t *testing.T) {
+	data := []byte{1, 2, 0x12, 0x34, 2, 3, 'a', 'b', 'c', 9, 1, 0xff}
+	state := BasicStateFromBytes(data)
+	field := TLV(Uint8, Uint8, func(tag interface{}) P {
+		switch tag.(uint8) {
+		case 1:
+			return Uint16BE
+		case 2:
+			return Many(One).Bind(func(x interface{}) P {
+				b := []byte{}
+				for _, e := range x.([]interface{}) {
+					b = append(b, e.(byte))
+				}
+				return Return(string(b))
+			})
+		}
+		return nil
+	})
+	re, err := Many(field).Over(EOF).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []interface{}{
+		TLVRecord{uint8(1), 2, uint16(0x1234)},
+		TLVRecord{uint8(2), 3, "abc"},
+		TLVRecord{uint8(9), 1, []byte{0xff}},
+	}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %v but %v", expect, re)
+	}
+}
@@ -0,0 +1,181 @@
+package grammar
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 打印时的优先级，数值越大结合越紧
+const (
+	precChoice = iota
+	precSeq
+	precPostfix
+	precAtom
+)
+
+// String 将结点打印成 W3C 风格的 EBNF 表达式。Try 和 Bind 不改变语言本身，打印时是透明的；
+// 向前查看借用 PEG 的 &e 和 !e ，谓词、Prim 之类无法展开的结点打印成 <name> 的形式。
+func (n *Node) String() string {
+	var b strings.Builder
+	writeNode(&b, n, precChoice)
+	return b.String()
+}
+
+// EBNF 按定义顺序将所有规则打印成 name ::= expr 的形式，每行一条
+func (g *Grammar) EBNF() string {
+	var b strings.Builder
+	for _, name := range g.names {
+		fmt.Fprintf(&b, "%s ::= %s\n", name, g.rules[name])
+	}
+	return b.String()
+}
+
+func precedence(n *Node) int {
+	switch n.Kind {
+	case KindChoice:
+		switch len(n.Children) {
+		case 0:
+			return precAtom
+		case 1:
+			return precedence(n.Children[0])
+		}
+		return precChoice
+	case KindSeq, KindThen, KindOver, KindSepBy1:
+		return precSeq
+	case KindMany, KindMany1, KindOption, KindSepBy:
+		return precPostfix
+	case KindTry, KindBind:
+		return precedence(n.Children[0])
+	case KindAhead, KindNot:
+		return precPostfix
+	case KindChr, KindStr:
+		if len(quote(n.Text)) > 1 {
+			return precSeq
+		}
+	}
+	return precAtom
+}
+
+func writeNode(b *strings.Builder, n *Node, prec int) {
+	if n.Kind == KindTry || n.Kind == KindBind {
+		writeNode(b, n.Children[0], prec)
+		return
+	}
+	if precedence(n) < prec {
+		b.WriteString("(")
+		defer b.WriteString(")")
+	}
+	switch n.Kind {
+	case KindChr, KindStr:
+		b.WriteString(strings.Join(quote(n.Text), " "))
+	case KindNChr, KindRuneNone:
+		b.WriteString("[^" + class(n.Text) + "]")
+	case KindRuneOf:
+		b.WriteString("[" + class(n.Text) + "]")
+	case KindRuneP, KindPrim:
+		b.WriteString("<" + n.Text + ">")
+	case KindOne:
+		b.WriteString("<any>")
+	case KindEOF:
+		b.WriteString("<eof>")
+	case KindReturn:
+		b.WriteString("()")
+	case KindFail:
+		b.WriteString("<fail>")
+	case KindSeq, KindThen, KindOver:
+		if len(n.Children) == 0 {
+			b.WriteString("()")
+		}
+		for i, child := range n.Children {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			writeNode(b, child, precSeq+1)
+		}
+	case KindChoice:
+		if len(n.Children) == 0 {
+			b.WriteString("<fail>")
+		}
+		for i, child := range n.Children {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			writeNode(b, child, precSeq)
+		}
+	case KindMany:
+		writeNode(b, n.Children[0], precAtom)
+		b.WriteString("*")
+	case KindMany1:
+		writeNode(b, n.Children[0], precAtom)
+		b.WriteString("+")
+	case KindOption:
+		writeNode(b, n.Children[0], precAtom)
+		b.WriteString("?")
+	case KindAhead:
+		b.WriteString("&")
+		writeNode(b, n.Children[0], precPostfix)
+	case KindNot:
+		b.WriteString("!")
+		writeNode(b, n.Children[0], precPostfix)
+	case KindSepBy1:
+		writeSepBy1(b, n)
+	case KindSepBy:
+		b.WriteString("(")
+		writeSepBy1(b, n)
+		b.WriteString(")?")
+	case KindRef:
+		b.WriteString(n.Text)
+	}
+}
+
+func writeSepBy1(b *strings.Builder, n *Node) {
+	writeNode(b, n.Children[0], precSeq+1)
+	b.WriteString(" (")
+	writeNode(b, n.Children[1], precSeq+1)
+	b.WriteString(" ")
+	writeNode(b, n.Children[0], precSeq+1)
+	b.WriteString(")*")
+}
+
+// quote 用引号包围字面量，无法直接书写的字符使用 #xN 形式，因此结果可能由多段组成
+func quote(text string) []string {
+	if text == "" {
+		return []string{"()"}
+	}
+	q := "\""
+	if strings.ContainsRune(text, '"') {
+		q = "'"
+	}
+	var parts []string
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			parts = append(parts, q+buf.String()+q)
+			buf.Reset()
+		}
+	}
+	for _, r := range text {
+		if !unicode.IsPrint(r) || string(r) == q {
+			flush()
+			parts = append(parts, fmt.Sprintf("#x%X", r))
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	flush()
+	return parts
+}
+
+// class 生成字符集合的内容，特殊字符使用 #xN 形式
+func class(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if !unicode.IsPrint(r) || strings.ContainsRune(`]^-\`, r) {
+			fmt.Fprintf(&b, "#x%X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,278 @@
+package grammar
+
+import (
+	"fmt"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Grammar 是一组具名规则，规则之间通过 Ref 相互引用
+type Grammar struct {
+	// Dispatch 为 true 时，Choice 会按照各分支的 FIRST 集编译成 goP2.Dispatch 。
+	// 分支失败时会消费输入的 Choice （例如 Choice(Chr('a'), Chr('b'))）编译之后可能接受更多的输入，
+	// 参见 goP2.Dispatch ；分支都用 Try 包装时两种编译方式的结果相同
+	Dispatch bool
+	names    []string
+	rules    map[string]*Node
+}
+
+// New 构造一个空的 Grammar
+func New() *Grammar {
+	return &Grammar{rules: make(map[string]*Node)}
+}
+
+// Define 定义或者替换一条规则，返回指向该规则的 Ref 结点
+func (g *Grammar) Define(name string, node *Node) *Node {
+	if _, ok := g.rules[name]; !ok {
+		g.names = append(g.names, name)
+	}
+	g.rules[name] = node
+	return Ref(name)
+}
+
+// Rule 返回指定名字的规则
+func (g *Grammar) Rule(name string) (*Node, bool) {
+	node, ok := g.rules[name]
+	return node, ok
+}
+
+// Names 按定义顺序返回所有规则名
+func (g *Grammar) Names() []string {
+	re := make([]string, len(g.names))
+	copy(re, g.names)
+	return re
+}
+
+// Check 检查所有 Ref 是否都指向已定义的规则
+func (g *Grammar) Check() error {
+	var err error
+	for _, name := range g.names {
+		Walk(g.rules[name], func(n *Node) bool {
+			if n.Kind == KindRef && err == nil {
+				if _, ok := g.rules[n.Text]; !ok {
+					err = fmt.Errorf("rule %s refers to undefined rule %s", name, n.Text)
+				}
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compile 将以 start 为起点的规则编译成 goP2.P
+func (g *Grammar) Compile(start string) (goP2.P, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.rules[start]; !ok {
+		return nil, fmt.Errorf("undefined rule %s", start)
+	}
+	c := g.compiler()
+	return c.rule(start), nil
+}
+
+// CompileNode 将一个结点编译成 goP2.P ，结点中的 Ref 在 g 中查找
+func (g *Grammar) CompileNode(node *Node) (goP2.P, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	var err error
+	Walk(node, func(n *Node) bool {
+		if n.Kind == KindRef && err == nil {
+			if _, ok := g.rules[n.Text]; !ok {
+				err = fmt.Errorf("undefined rule %s", n.Text)
+			}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.compiler().compile(node), nil
+}
+
+// Compile 编译一个不包含 Ref 的结点
+func Compile(node *Node) (goP2.P, error) {
+	return New().CompileNode(node)
+}
+
+// First 计算结点的 FIRST 集，递归引用的规则视为未知
+func (g *Grammar) First(node *Node) goP2.FirstSet {
+	return g.first(node, make(map[string]bool))
+}
+
+func (g *Grammar) first(n *Node, visiting map[string]bool) goP2.FirstSet {
+	switch n.Kind {
+	case KindChr:
+		return goP2.FirstOf([]rune(n.Text)[0])
+	case KindStr:
+		data := []rune(n.Text)
+		if len(data) == 0 {
+			return goP2.EmptyFirst
+		}
+		return goP2.FirstOf(data[0])
+	case KindRuneOf:
+		elements := make([]interface{}, 0, len(n.Text))
+		for _, r := range n.Text {
+			elements = append(elements, r)
+		}
+		return goP2.FirstOf(elements...)
+	case KindEOF, KindReturn:
+		return goP2.EmptyFirst
+	case KindFail:
+		// Fail 常用作 Choice 的最后一个分支来给出自定义的错误信息，视为未知才会总是被尝试
+		return goP2.UnknownFirst
+	case KindSeq, KindThen, KindOver:
+		first := goP2.EmptyFirst
+		for _, child := range n.Children {
+			first = first.Then(g.first(child, visiting))
+		}
+		return first
+	case KindChoice:
+		first := goP2.FirstOf()
+		for _, child := range n.Children {
+			first = first.Union(g.first(child, visiting))
+		}
+		return first
+	case KindTry, KindMany1, KindSepBy1:
+		return g.first(n.Children[0], visiting)
+	case KindMany, KindSepBy, KindOption:
+		return g.first(n.Children[0], visiting).Union(goP2.EmptyFirst)
+	case KindAhead:
+		first := g.first(n.Children[0], visiting)
+		if first.Known {
+			first.Nullable = true
+		}
+		return first
+	case KindNot:
+		return goP2.UnknownFirst
+	case KindBind:
+		first := g.first(n.Children[0], visiting)
+		if first.Nullable {
+			return goP2.UnknownFirst
+		}
+		return first
+	case KindRef:
+		rule, ok := g.rules[n.Text]
+		if !ok || visiting[n.Text] {
+			return goP2.UnknownFirst
+		}
+		visiting[n.Text] = true
+		defer delete(visiting, n.Text)
+		return g.first(rule, visiting)
+	case KindPrim:
+		return n.First
+	}
+	return goP2.UnknownFirst
+}
+
+type compiler struct {
+	g     *Grammar
+	rules map[string]*goP2.P
+}
+
+func (g *Grammar) compiler() *compiler {
+	return &compiler{g, make(map[string]*goP2.P)}
+}
+
+// rule 返回规则的算子，规则只编译一次，并通过间接引用支持递归
+func (c *compiler) rule(name string) goP2.P {
+	ref, ok := c.rules[name]
+	if !ok {
+		ref = new(goP2.P)
+		c.rules[name] = ref
+		*ref = c.compile(c.g.rules[name])
+	}
+	return func(state goP2.State) (interface{}, error) {
+		return (*ref)(state)
+	}
+}
+
+func (c *compiler) compile(n *Node) goP2.P {
+	switch n.Kind {
+	case KindChr:
+		return goP2.Chr([]rune(n.Text)[0])
+	case KindNChr:
+		return goP2.NChr([]rune(n.Text)[0])
+	case KindStr:
+		return goP2.Str(n.Text)
+	case KindRuneOf:
+		return goP2.RuneOf(n.Text)
+	case KindRuneNone:
+		return goP2.RuneNone(n.Text)
+	case KindRuneP:
+		return goP2.RuneP(n.Text, n.Pred)
+	case KindOne:
+		return goP2.One
+	case KindEOF:
+		return goP2.EOF
+	case KindReturn:
+		return goP2.Return(n.Value)
+	case KindFail:
+		return goP2.Fail("%s", n.Text)
+	case KindSeq:
+		return goP2.UnionAll(c.children(n)...)
+	case KindThen:
+		return c.compile(n.Children[0]).Then(c.compile(n.Children[1]))
+	case KindOver:
+		return c.compile(n.Children[0]).Over(c.compile(n.Children[1]))
+	case KindChoice:
+		if c.g.Dispatch {
+			return c.dispatch(n)
+		}
+		return goP2.Choice(c.children(n)...)
+	case KindTry:
+		return goP2.Try(c.compile(n.Children[0]))
+	case KindMany:
+		return goP2.Many(c.compile(n.Children[0]))
+	case KindMany1:
+		return goP2.Many1(c.compile(n.Children[0]))
+	case KindSepBy:
+		return goP2.SepBy(c.compile(n.Children[0]), c.compile(n.Children[1]))
+	case KindSepBy1:
+		return goP2.SepBy1(c.compile(n.Children[0]), c.compile(n.Children[1]))
+	case KindOption:
+		return goP2.Option(n.Value, c.compile(n.Children[0]))
+	case KindAhead:
+		return goP2.Ahead(c.compile(n.Children[0]))
+	case KindNot:
+		return c.not(n)
+	case KindBind:
+		return c.compile(n.Children[0]).Bind(n.Binder)
+	case KindRef:
+		return c.rule(n.Text)
+	case KindPrim:
+		return n.P
+	}
+	panic(fmt.Sprintf("unknown node kind %v", n.Kind))
+}
+
+func (c *compiler) not(n *Node) goP2.P {
+	p := goP2.Ahead(c.compile(n.Children[0]))
+	return func(state goP2.State) (interface{}, error) {
+		_, err := p(state)
+		if err == nil {
+			return nil, state.Trap("Unexpected %v", n.Children[0])
+		}
+		return nil, nil
+	}
+}
+
+func (c *compiler) children(n *Node) []goP2.P {
+	re := make([]goP2.P, 0, len(n.Children))
+	for _, child := range n.Children {
+		re = append(re, c.compile(child))
+	}
+	return re
+}
+
+func (c *compiler) dispatch(n *Node) goP2.P {
+	fs := make([]goP2.F, 0, len(n.Children))
+	for _, child := range n.Children {
+		fs = append(fs, goP2.WithFirst(c.compile(child), c.g.First(child)))
+	}
+	return goP2.Dispatch(fs...).P
+}
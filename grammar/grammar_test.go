@@ -0,0 +1,113 @@
+package grammar
+
+import (
+	"testing"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func listGrammar() *Grammar {
+	g := New()
+	digit := RuneP("digit", unicode.IsDigit)
+	g.Define("list", Seq(Chr('['), SepBy(Ref("item"), Chr(',')), Chr(']')))
+	g.Define("item", Choice(Try(Ref("number")), Ref("list")))
+	g.Define("number", Many1(digit))
+	return g
+}
+
+func TestEBNF(t *testing.T) {
+	g := listGrammar()
+	expect := "list ::= \"[\" (item (\",\" item)*)? \"]\"\n" +
+		"item ::= number | list\n" +
+		"number ::= <digit>+\n"
+	if re := g.EBNF(); re != expect {
+		t.Fatalf("Expect\n%s but\n%s", expect, re)
+	}
+}
+
+func TestEBNFParens(t *testing.T) {
+	node := Many(Choice(Str("ab"), Seq(RuneOf("x]"), Chr('\n'))))
+	expect := "(\"ab\" | [x#x5D] #xA)*"
+	if re := node.String(); re != expect {
+		t.Fatalf("Expect %s but %s", expect, re)
+	}
+	// Try 是透明的，不会在已经加上的括号之外再加一层
+	node = Many(Try(Choice(Str("ab"), Chr('c'))))
+	if re := node.String(); re != "(\"ab\" | \"c\")*" {
+		t.Fatalf("Expect (\"ab\" | \"c\")* but %s", re)
+	}
+	// 只有一个分支的 Choice 与它的分支优先级相同
+	node = Many(Choice(Seq(Str("a"), Str("b"))))
+	if re := node.String(); re != "(\"a\" \"b\")*" {
+		t.Fatalf("Expect (\"a\" \"b\")* but %s", re)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, dispatch := range []bool{false, true} {
+		g := listGrammar()
+		g.Dispatch = dispatch
+		p, err := g.Compile("list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := goP2.BasicStateFromText("[1,[22,3],[]]")
+		_, err = p.Then(goP2.EOF).Parse(&state)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompileFail(t *testing.T) {
+	for _, dispatch := range []bool{false, true} {
+		g := New()
+		g.Dispatch = dispatch
+		g.Define("start", Choice(Try(Chr('a')), Try(Chr('b')), Fail("expect a or b")))
+		p, err := g.Compile("start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := goP2.BasicStateFromText("c")
+		_, err = p.Parse(&state)
+		if e, ok := err.(goP2.Error); !ok || e.Message != "expect a or b" {
+			t.Fatalf("dispatch %v: expect the Fail message but %v", dispatch, err)
+		}
+	}
+}
+
+func TestCompileUndefined(t *testing.T) {
+	g := New()
+	g.Define("start", Seq(Ref("missing"), EOF()))
+	if _, err := g.Compile("start"); err == nil {
+		t.Fatalf("Expect a error for undefined rule")
+	}
+}
+
+func TestFirst(t *testing.T) {
+	g := listGrammar()
+	first := g.First(Ref("item"))
+	if first.Known {
+		t.Fatalf("Expect unknown first set but %v", first)
+	}
+	first = g.First(Ref("list"))
+	if !first.Accept('[') || first.Accept('1') {
+		t.Fatalf("Expect first set ['['] but %v", first)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	g := listGrammar()
+	rule, _ := g.Rule("list")
+	var refs []string
+	Walk(rule, func(n *Node) bool {
+		if n.Kind == KindRef {
+			refs = append(refs, n.Text)
+		}
+		return true
+	})
+	if len(refs) != 1 || refs[0] != "item" {
+		t.Fatalf("Expect [item] but %v", refs)
+	}
+}
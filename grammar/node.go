@@ -0,0 +1,223 @@
+// Package grammar 提供 goP2 算子的可内省表示。这里的组合子与 goP2 中的同名算子一一对应，
+// 但它们生成的是可以遍历、打印成 EBNF 以及重新编译成 goP2.P 的结点图，可以作为可视化、
+// 分析和优化的基础。
+package grammar
+
+import (
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Kind 是结点的种类
+type Kind int
+
+// 结点种类与 goP2 中的算子对应
+const (
+	KindChr Kind = iota
+	KindNChr
+	KindStr
+	KindRuneOf
+	KindRuneNone
+	KindRuneP
+	KindOne
+	KindEOF
+	KindReturn
+	KindFail
+	KindSeq
+	KindThen
+	KindOver
+	KindChoice
+	KindTry
+	KindMany
+	KindMany1
+	KindSepBy
+	KindSepBy1
+	KindOption
+	KindAhead
+	KindNot
+	KindBind
+	KindRef
+	KindPrim
+)
+
+var kindNames = [...]string{
+	KindChr:      "Chr",
+	KindNChr:     "NChr",
+	KindStr:      "Str",
+	KindRuneOf:   "RuneOf",
+	KindRuneNone: "RuneNone",
+	KindRuneP:    "RuneP",
+	KindOne:      "One",
+	KindEOF:      "EOF",
+	KindReturn:   "Return",
+	KindFail:     "Fail",
+	KindSeq:      "Seq",
+	KindThen:     "Then",
+	KindOver:     "Over",
+	KindChoice:   "Choice",
+	KindTry:      "Try",
+	KindMany:     "Many",
+	KindMany1:    "Many1",
+	KindSepBy:    "SepBy",
+	KindSepBy1:   "SepBy1",
+	KindOption:   "Option",
+	KindAhead:    "Ahead",
+	KindNot:      "Not",
+	KindBind:     "Bind",
+	KindRef:      "Ref",
+	KindPrim:     "Prim",
+}
+
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(?)"
+}
+
+// Node 是语法图中的一个结点。Text 保存字面量、字符集合、规则名或者谓词名，
+// Children 保存子结点，其余字段只在对应种类的结点上有意义。
+type Node struct {
+	Kind     Kind
+	Text     string
+	Children []*Node
+	Value    interface{}
+	Pred     func(rune) bool
+	Binder   func(interface{}) goP2.P
+	P        goP2.P
+	First    goP2.FirstSet
+}
+
+// Chr 对应 goP2.Chr
+func Chr(val rune) *Node {
+	return &Node{Kind: KindChr, Text: string([]rune{val})}
+}
+
+// NChr 对应 goP2.NChr
+func NChr(val rune) *Node {
+	return &Node{Kind: KindNChr, Text: string([]rune{val})}
+}
+
+// Str 对应 goP2.Str
+func Str(str string) *Node {
+	return &Node{Kind: KindStr, Text: str}
+}
+
+// RuneOf 对应 goP2.RuneOf
+func RuneOf(str string) *Node {
+	return &Node{Kind: KindRuneOf, Text: str}
+}
+
+// RuneNone 对应 goP2.RuneNone
+func RuneNone(str string) *Node {
+	return &Node{Kind: KindRuneNone, Text: str}
+}
+
+// RuneP 对应 goP2.RuneP ，name 会作为打印时的名字
+func RuneP(name string, pred func(r rune) bool) *Node {
+	return &Node{Kind: KindRuneP, Text: name, Pred: pred}
+}
+
+// One 对应 goP2.One
+func One() *Node {
+	return &Node{Kind: KindOne}
+}
+
+// EOF 对应 goP2.EOF
+func EOF() *Node {
+	return &Node{Kind: KindEOF}
+}
+
+// Return 对应 goP2.Return
+func Return(val interface{}) *Node {
+	return &Node{Kind: KindReturn, Value: val}
+}
+
+// Fail 对应 goP2.Fail
+func Fail(message string) *Node {
+	return &Node{Kind: KindFail, Text: message}
+}
+
+// Seq 顺序匹配给定的结点，对应 goP2.UnionAll
+func Seq(nodes ...*Node) *Node {
+	return &Node{Kind: KindSeq, Children: nodes}
+}
+
+// Choice 对应 goP2.Choice
+func Choice(nodes ...*Node) *Node {
+	return &Node{Kind: KindChoice, Children: nodes}
+}
+
+// Try 对应 goP2.Try
+func Try(node *Node) *Node {
+	return &Node{Kind: KindTry, Children: []*Node{node}}
+}
+
+// Many 对应 goP2.Many
+func Many(node *Node) *Node {
+	return &Node{Kind: KindMany, Children: []*Node{node}}
+}
+
+// Many1 对应 goP2.Many1
+func Many1(node *Node) *Node {
+	return &Node{Kind: KindMany1, Children: []*Node{node}}
+}
+
+// SepBy 对应 goP2.SepBy
+func SepBy(node, sep *Node) *Node {
+	return &Node{Kind: KindSepBy, Children: []*Node{node, sep}}
+}
+
+// SepBy1 对应 goP2.SepBy1
+func SepBy1(node, sep *Node) *Node {
+	return &Node{Kind: KindSepBy1, Children: []*Node{node, sep}}
+}
+
+// Option 对应 goP2.Option
+func Option(v interface{}, node *Node) *Node {
+	return &Node{Kind: KindOption, Value: v, Children: []*Node{node}}
+}
+
+// Ahead 对应 goP2.Ahead ，即 PEG 中的 &e
+func Ahead(node *Node) *Node {
+	return &Node{Kind: KindAhead, Children: []*Node{node}}
+}
+
+// Not 是否定的向前查看，即 PEG 中的 !e ，node 匹配成功时失败，否则返回 nil 且不消费输入
+func Not(node *Node) *Node {
+	return &Node{Kind: KindNot, Children: []*Node{node}}
+}
+
+// Ref 引用 Grammar 中的一条规则，规则可以在引用之后再定义，以便表达递归
+func Ref(name string) *Node {
+	return &Node{Kind: KindRef, Text: name}
+}
+
+// Prim 将一个不透明的 goP2.P 嵌入语法图，name 用于打印，first 是它的 FIRST 集
+func Prim(name string, p goP2.P, first goP2.FirstSet) *Node {
+	return &Node{Kind: KindPrim, Text: name, P: p, First: first}
+}
+
+// Then 对应 P 的 Then 方法
+func (n *Node) Then(m *Node) *Node {
+	return &Node{Kind: KindThen, Children: []*Node{n, m}}
+}
+
+// Over 对应 P 的 Over 方法
+func (n *Node) Over(m *Node) *Node {
+	return &Node{Kind: KindOver, Children: []*Node{n, m}}
+}
+
+// Bind 对应 P 的 Bind 方法。binder 生成的算子是不透明的，语法图中只保留 n
+func (n *Node) Bind(binder func(interface{}) goP2.P) *Node {
+	return &Node{Kind: KindBind, Children: []*Node{n}, Binder: binder}
+}
+
+// Walk 按先序遍历结点图，visit 返回 false 时不再进入该结点的子结点。Walk 不会跟随 Ref
+func Walk(n *Node, visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.Children {
+		Walk(child, visit)
+	}
+}
@@ -0,0 +1,190 @@
+package goP2
+
+// SourcePos 是从 1 开始的行列位置，每个元素（rune）占一列，换行符 '\n' 开始新的一行
+type SourcePos struct {
+	Line   int
+	Column int
+}
+
+// Advance 返回从 p 开始经过 text 之后的行列位置
+func (p SourcePos) Advance(text []rune) SourcePos {
+	for _, r := range text {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
+// LineCol 返回 text 中以 rune 计的偏移 offset 处的行列位置，超出文本的偏移落在文本的结尾
+func LineCol(text []rune, offset int) SourcePos {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return SourcePos{1, 1}.Advance(text[:offset])
+}
+
+// IndentState 在给定的 State 之上记录缩进的参照位置，用于 Indented 、 Block 、 WithPos 和
+// CheckIndent 这些缩进敏感的算子。行列位置按照顺序解析的习惯增量计算并缓存
+type IndentState struct {
+	State
+	ref SourcePos
+	// 最近一次计算的位置
+	cachePos int
+	cacheAt  SourcePos
+}
+
+// NewIndentState 构造一个新的 IndentState ，初始的参照位置是第 1 行第 1 列
+func NewIndentState(state State) *IndentState {
+	return &IndentState{
+		State:    state,
+		ref:      SourcePos{1, 1},
+		cachePos: 0,
+		cacheAt:  SourcePos{1, 1},
+	}
+}
+
+// restore 将 state 移回 pos ， BasicState 无法直接移动到结尾，此时先移到前一个位置再读取一次
+func restore(state State, pos int) {
+	if !state.SeekTo(pos) && pos > 0 {
+		state.SeekTo(pos - 1)
+		state.Next()
+	}
+}
+
+// scan 从 from 位置的 at 开始向前扫描到 to ，返回 to 处的行列位置
+func scan(state State, from int, at SourcePos, to int) SourcePos {
+	if from == to {
+		return at
+	}
+	origin := state.Pos()
+	state.SeekTo(from)
+	for i := from; i < to; i++ {
+		x, err := state.Next()
+		if err != nil {
+			break
+		}
+		if x == '\n' {
+			at.Line++
+			at.Column = 1
+		} else {
+			at.Column++
+		}
+	}
+	restore(state, origin)
+	return at
+}
+
+// Position 返回当前的行列位置
+func (s *IndentState) Position() SourcePos {
+	pos := s.Pos()
+	if pos < s.cachePos {
+		s.cachePos, s.cacheAt = 0, SourcePos{1, 1}
+	}
+	s.cacheAt = scan(s.State, s.cachePos, s.cacheAt, pos)
+	s.cachePos = pos
+	return s.cacheAt
+}
+
+// Unwrap 返回被包装的 State
+func (s *IndentState) Unwrap() State {
+	return s.State
+}
+
+// Reference 返回当前缩进的参照位置
+func (s *IndentState) Reference() SourcePos {
+	return s.ref
+}
+
+// locator 是实现了 Position 方法的 State
+type locator interface {
+	Position() SourcePos
+}
+
+// findLocator 沿着 Unwrap 链查找实现了 Position 方法的 State
+func findLocator(state State) (locator, bool) {
+	s, ok := findState(state, func(s State) bool {
+		_, ok := s.(locator)
+		return ok
+	})
+	if !ok {
+		return nil, false
+	}
+	return s.(locator), true
+}
+
+// Position 返回 state 当前的行列位置。 state 或者它包装的 State 实现了 Position 方法（例如 IndentState）时
+// 直接使用它，否则从头扫描输入
+func Position(state State) SourcePos {
+	if s, ok := findLocator(state); ok {
+		return s.Position()
+	}
+	return scan(state, 0, SourcePos{1, 1}, state.Pos())
+}
+
+// Column 返回 state 当前的列号
+func Column(state State) int {
+	return Position(state).Column
+}
+
+// indentState 沿着 Unwrap 链从 state 中取出 IndentState
+func indentState(state State) (*IndentState, error) {
+	s, ok := findState(state, func(s State) bool {
+		_, ok := s.(*IndentState)
+		return ok
+	})
+	if !ok {
+		return nil, Raise(state, "indent.state", state)
+	}
+	return s.(*IndentState), nil
+}
+
+// WithPos 以当前位置为缩进的参照位置运行 p ，之后恢复原来的参照位置
+func WithPos(p P) P {
+	return func(state State) (interface{}, error) {
+		s, err := indentState(state)
+		if err != nil {
+			return nil, err
+		}
+		saved := s.ref
+		s.ref = s.Position()
+		defer func() { s.ref = saved }()
+		return p(state)
+	}
+}
+
+// CheckIndent 在当前列与参照位置的列相同时成功，不消费输入
+func CheckIndent(state State) (interface{}, error) {
+	s, err := indentState(state)
+	if err != nil {
+		return nil, err
+	}
+	if col := s.Position().Column; col != s.ref.Column {
+		return nil, Raise(state, "indent.column", s.ref.Column, col)
+	}
+	return nil, nil
+}
+
+// Indented 在当前列大于参照位置的列时成功，不消费输入
+func Indented(state State) (interface{}, error) {
+	s, err := indentState(state)
+	if err != nil {
+		return nil, err
+	}
+	if col := s.Position().Column; col <= s.ref.Column {
+		return nil, Raise(state, "indent.greater", s.ref.Column, col)
+	}
+	return nil, nil
+}
+
+// Block 以第一项的位置为参照，匹配一到若干个起始于同一列的 p ，返回结果序列。
+// p 需要自己消费项目之后的空白和换行，使下一项从行首的缩进处开始
+func Block(p P) P {
+	return WithPos(Many1(P(CheckIndent).Then(p)))
+}
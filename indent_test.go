@@ -0,0 +1,136 @@
+package goP2
+
+import (
+	"reflect"
+	"testing"
+)
+
+// outline 是一个简单的缩进文法：名字之后跟着冒号时，下一行开始一个缩进更深的子块
+type outline struct {
+	Name     string
+	Children []outline
+}
+
+var blankLines = Skip(RuneOf(" \n"))
+
+var outlineName = Many1(RuneOf("abcdefghijklmnopqrstuvwxyz")).Bind(ReturnString)
+
+func outlineItem(state State) (interface{}, error) {
+	return Do(func(state State) interface{} {
+		re := outline{Name: outlineName.Exec(state).(string)}
+		if _, err := Try(Chr(':')).Parse(state); err != nil {
+			blankLines.Exec(state)
+			return re
+		}
+		blankLines.Exec(state)
+		P(Indented).Exec(state)
+		for _, child := range Block(outlineItem).Exec(state).([]interface{}) {
+			re.Children = append(re.Children, child.(outline))
+		}
+		return re
+	})(state)
+}
+
+func TestBlock(t *testing.T) {
+	text := "a:\n  b\n  c:\n      d\n\n      e\n  f\ng\n"
+	base := BasicStateFromText(text)
+	state := NewIndentState(&base)
+	re, err := Block(outlineItem).Over(EOF).Parse(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []interface{}{
+		outline{"a", []outline{
+			{"b", nil},
+			{"c", []outline{{"d", nil}, {"e", nil}}},
+			{"f", nil},
+		}},
+		outline{"g", nil},
+	}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %v but %v", expect, re)
+	}
+}
+
+func TestBlockWrapped(t *testing.T) {
+	text := "a:\n  b\n  c\n"
+	expect := []interface{}{outline{"a", []outline{{"b", nil}, {"c", nil}}}}
+	for name, wrap := range map[string]func(State) State{
+		"UserState over IndentState": func(s State) State { return NewUserState(NewIndentState(s), nil) },
+		"IndentState over UserState": func(s State) State { return NewIndentState(NewUserState(s, nil)) },
+	} {
+		base := BasicStateFromText(text)
+		re, err := Block(outlineItem).Over(EOF).Parse(wrap(&base))
+		if err != nil || !reflect.DeepEqual(re, expect) {
+			t.Errorf("%s: Expect %v but %v, %v", name, expect, re, err)
+		}
+	}
+
+	// Sized 的窗口包装在 IndentState 之外
+	base := BasicStateFromText("  ab")
+	state := NewIndentState(&base)
+	p := Skip(Chr(' ')).Then(Sized(2, P(Indented).Then(WithPos(P(CheckIndent).Then(Many(One))))))
+	if _, err := p.Parse(state); err != nil {
+		t.Fatalf("Expect indentation combinators inside Sized but %v", err)
+	}
+}
+
+func TestBadIndent(t *testing.T) {
+	for _, text := range []string{
+		// 子块没有缩进
+		"a:\nb\n",
+		// 同一个块中的项目没有对齐
+		"a:\n  b\n   c\n",
+	} {
+		base := BasicStateFromText(text)
+		state := NewIndentState(&base)
+		if re, err := Block(outlineItem).Over(EOF).Parse(state); err == nil {
+			t.Errorf("%q: Expect error but %v", text, re)
+		}
+	}
+	base := BasicStateFromText("a")
+	if _, err := CheckIndent(&base); err == nil {
+		t.Errorf("Expect error for a state without indentation support")
+	}
+}
+
+func TestPosition(t *testing.T) {
+	base := BasicStateFromText("ab\ncd\n")
+	state := NewIndentState(&base)
+	for _, expect := range []SourcePos{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}, {2, 3}, {3, 1}} {
+		if got := state.Position(); got != expect {
+			t.Fatalf("Expect %v but %v at %d", expect, got, state.Pos())
+		}
+		if got := Position(&base); got != expect {
+			t.Fatalf("Expect %v from a basic state but %v", expect, got)
+		}
+		state.Next()
+	}
+	if state.Pos() != 6 {
+		t.Fatalf("Expect position to stay at the end but %d", state.Pos())
+	}
+	state.SeekTo(1)
+	if got := Column(state); got != 2 {
+		t.Fatalf("Expect column 2 after seeking back but %d", got)
+	}
+}
+
+func TestLineCol(t *testing.T) {
+	text := []rune("ab\n名字\nc")
+	for _, c := range []struct {
+		offset int
+		expect SourcePos
+	}{
+		{0, SourcePos{1, 1}},
+		{2, SourcePos{1, 3}},
+		{3, SourcePos{2, 1}},
+		{5, SourcePos{2, 3}},
+		{7, SourcePos{3, 2}},
+		{100, SourcePos{3, 2}},
+		{-1, SourcePos{1, 1}},
+	} {
+		if got := LineCol(text, c.offset); got != c.expect {
+			t.Fatalf("%d: expect %v but %v", c.offset, c.expect, got)
+		}
+	}
+}
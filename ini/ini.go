@@ -0,0 +1,227 @@
+// Package ini 是基于 goP2 的 INI 配置解析器。
+//
+// 解析结果为嵌套的 map[string]interface{} ：节之外的键值位于顶层，节名中的点号表示嵌套，
+// 例如 [server.http] 中的键位于 re["server"]["http"] 之下。
+package ini
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Options 控制解析的细节
+type Options struct {
+	// Typed 为 true 时，未加引号的 true/false 、整数和浮点数分别转换为 bool 、 int64 和 float64 ，
+	// 否则所有的值都是 string
+	Typed bool
+	// AllowDuplicates 为 true 时，同一节中重复的键以最后一次出现为准，否则报错
+	AllowDuplicates bool
+}
+
+// ParseError 是带有行列位置的解析错误，行列都从 1 开始
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ini: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func parseError(data []rune, err error) *ParseError {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return &ParseError{1, 1, err.Error()}
+	}
+	at := goP2.LineCol(data, e.Pos)
+	return &ParseError{at.Line, at.Column, e.Message}
+}
+
+var ws = goP2.Skip(goP2.RuneOf(" \t"))
+
+var eol = goP2.Choice(goP2.Try(goP2.Str("\r\n")), goP2.Try(goP2.RuneOf("\r\n")), goP2.EOF)
+
+var comment = goP2.RuneOf(";#").Then(goP2.Skip(goP2.RuneNone("\r\n")))
+
+// rest 匹配行尾的空白、可选的注释以及行结束符
+var rest = ws.Then(goP2.Maybe(goP2.Try(comment))).Then(eol)
+
+// Section 解析 [name] 节头，返回按点号拆分的节名
+var Section = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Chr('[').Exec(state)
+	name := goP2.ToString(goP2.Many(goP2.RuneNone("]\r\n")).Exec(state))
+	if _, err := goP2.Try(goP2.Chr(']')).Parse(state); err != nil {
+		panic(state.Trap("Expect ']' after section name"))
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+		if parts[i] == "" {
+			panic(state.Trap("empty section name in [%s]", name))
+		}
+	}
+	return parts
+})
+
+var escapes = map[rune]rune{'\\': '\\', '"': '"', 'n': '\n', 't': '\t', 'r': '\r'}
+
+// quoted 解析双引号字符串（支持 \\ \" \n \t \r 转义）或者单引号字符串（不处理转义）
+var quoted = goP2.Do(func(state goP2.State) interface{} {
+	q := goP2.RuneOf("\"'").Exec(state).(rune)
+	var b strings.Builder
+	for {
+		x, err := goP2.Try(goP2.RuneNone("\r\n")).Parse(state)
+		if err != nil {
+			panic(state.Trap("unterminated string"))
+		}
+		r := x.(rune)
+		switch {
+		case r == q:
+			return b.String()
+		case r == '\\' && q == '"':
+			x, err := goP2.Try(goP2.RuneNone("\r\n")).Parse(state)
+			if err != nil {
+				panic(state.Trap("unterminated string"))
+			}
+			c, ok := escapes[x.(rune)]
+			if !ok {
+				panic(state.Trap("invalid escape '\\%s' in string", string([]rune{x.(rune)})))
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune(r)
+		}
+	}
+})
+
+// unquoted 解析到行尾的值，空白之后的 ; 或者 # 开始行内注释，首尾空白会被去掉
+var unquoted = goP2.Do(func(state goP2.State) interface{} {
+	var b strings.Builder
+	space := false
+	for {
+		pos := state.Pos()
+		x, err := goP2.RuneNone("\r\n").Parse(state)
+		if err != nil {
+			state.SeekTo(pos)
+			break
+		}
+		r := x.(rune)
+		if (r == ';' || r == '#') && (space || b.Len() == 0) {
+			state.SeekTo(pos)
+			break
+		}
+		space = r == ' ' || r == '\t'
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+})
+
+// Key 解析键值对中的键，键在 = 或者 : 之前，首尾空白会被去掉
+var Key = goP2.Do(func(state goP2.State) interface{} {
+	key := strings.TrimSpace(goP2.ToString(goP2.Many(goP2.RuneNone("=:\r\n")).Exec(state)))
+	if key == "" {
+		panic(state.Trap("empty key"))
+	}
+	if _, err := goP2.Try(goP2.RuneOf("=:")).Parse(state); err != nil {
+		panic(state.Trap("Expect '=' or ':' after key %s", key))
+	}
+	return key
+})
+
+// typed 将未加引号的值转换为对应的类型
+func typed(text string) interface{} {
+	switch strings.ToLower(text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return v
+	}
+	return text
+}
+
+// section 沿着节名查找或者创建嵌套的表
+func section(state goP2.State, pos int, root map[string]interface{}, path []string) map[string]interface{} {
+	current := root
+	for i, name := range path {
+		child, ok := current[name]
+		if !ok {
+			child = make(map[string]interface{})
+			current[name] = child
+		}
+		table, ok := child.(map[string]interface{})
+		if !ok {
+			state.SeekTo(pos)
+			panic(state.Trap("key %s is not a section", strings.Join(path[:i+1], ".")))
+		}
+		current = table
+	}
+	return current
+}
+
+// Document 返回按照 opts 解析整个 INI 文本的算子
+func Document(opts Options) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		root := make(map[string]interface{})
+		current := root
+		for {
+			ws.Exec(state)
+			if _, err := goP2.Try(goP2.EOF).Parse(state); err == nil {
+				return root
+			}
+			pos := state.Pos()
+			if _, err := goP2.Try(rest).Parse(state); err == nil {
+				continue
+			}
+			if _, err := goP2.Ahead(goP2.Chr('[')).Parse(state); err == nil {
+				path := Section.Exec(state).([]string)
+				current = section(state, pos, root, path)
+			} else {
+				key := Key.Exec(state).(string)
+				ws.Exec(state)
+				var v interface{}
+				if _, err := goP2.Ahead(goP2.RuneOf("\"'")).Parse(state); err == nil {
+					v = quoted.Exec(state)
+				} else {
+					v = unquoted.Exec(state)
+					if opts.Typed {
+						v = typed(v.(string))
+					}
+				}
+				if old, ok := current[key]; ok {
+					if _, isTable := old.(map[string]interface{}); isTable || !opts.AllowDuplicates {
+						state.SeekTo(pos)
+						panic(state.Trap("duplicate key %s", key))
+					}
+				}
+				current[key] = v
+			}
+			ws.Exec(state)
+			if _, err := goP2.Try(rest).Parse(state); err != nil {
+				pos := state.Pos()
+				r, _ := state.Next()
+				state.SeekTo(pos)
+				panic(state.Trap("Expect end of line but %q", r))
+			}
+		}
+	})
+}
+
+// Parse 按照 opts 解析 INI 文本，错误总是 *ParseError
+func Parse(text string, opts Options) (map[string]interface{}, error) {
+	state := goP2.BasicStateFromText(text)
+	re, err := Document(opts).Parse(&state)
+	if err != nil {
+		return nil, parseError([]rune(text), err)
+	}
+	return re.(map[string]interface{}), nil
+}
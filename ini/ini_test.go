@@ -0,0 +1,101 @@
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const config = `; global settings
+name = demo
+debug: true
+
+[server]
+host = "0.0.0.0" ; listen on all interfaces
+port = 8080
+path = C:\data # trailing comment
+motd = 'no \n escapes'
+
+[server.tls]
+cert = "/etc/cert.pem"
+color = #fff
+`
+
+func TestParse(t *testing.T) {
+	re, err := Parse(config, Options{})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := map[string]interface{}{
+		"name":  "demo",
+		"debug": "true",
+		"server": map[string]interface{}{
+			"host": "0.0.0.0",
+			"port": "8080",
+			"path": `C:\data`,
+			"motd": `no \n escapes`,
+			"tls": map[string]interface{}{
+				"cert":  "/etc/cert.pem",
+				"color": "",
+			},
+		},
+	}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %v but %v", expect, re)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	re, err := Parse("a = 1\nb = 1.5\nc = TRUE\nd = \"2\"\ne = v1.0\n", Options{Typed: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := map[string]interface{}{"a": int64(1), "b": 1.5, "c": true, "d": "2", "e": "v1.0"}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %v but %v", expect, re)
+	}
+}
+
+func TestSectionMerge(t *testing.T) {
+	re, err := Parse("[a]\nx = 1\n[b]\n[a]\ny = 2\n", Options{})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if !reflect.DeepEqual(re["a"], map[string]interface{}{"x": "1", "y": "2"}) {
+		t.Fatalf("Expect merged section but %v", re["a"])
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	_, err := Parse("[a]\nx = 1\nx = 2\n", Options{})
+	e, ok := err.(*ParseError)
+	if !ok || e.Line != 3 || e.Column != 1 || !strings.Contains(e.Message, "duplicate key x") {
+		t.Fatalf("Expect duplicate key at line 3, column 1 but %v", err)
+	}
+	re, err := Parse("[a]\nx = 1\nx = 2\n", Options{AllowDuplicates: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if re["a"].(map[string]interface{})["x"] != "2" {
+		t.Fatalf("Expect last value wins but %v", re)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		line int
+	}{
+		{"a = 1\n[server\n", 2},
+		{"a = 1\njust text\n", 2},
+		{"a = \"open\n", 1},
+		{"a = \"x\" y\n", 1},
+		{"a = 1\n[a.b]\n", 2},
+	} {
+		_, err := Parse(c.text, Options{})
+		e, ok := err.(*ParseError)
+		if !ok || e.Line != c.line {
+			t.Errorf("%q: Expect error at line %d but %v", c.text, c.line, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package json 是基于 goP2 的 JSON 解析器。默认按照 RFC 8259 严格解析，
+// 可以通过 Options 打开注释、尾随逗号以及 JSON5 扩展。
+//
+// 解析结果使用与 encoding/json 解码到 interface{} 相同的类型：对象为 map[string]interface{} ，
+// 数组为 []interface{} ，数字为 float64 （或者 json.Number ），字符串为 string ，
+// 布尔值为 bool ， null 为 nil 。
+package json
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// DefaultMaxDepth 是 Options.MaxDepth 为 0 时使用的嵌套深度上限
+const DefaultMaxDepth = 1000
+
+// Options 控制解析的方言
+type Options struct {
+	// UseNumber 为 true 时数字解析为 json.Number ，而不是 float64
+	UseNumber bool
+	// Comments 允许 // 行注释和 /* */ 块注释
+	Comments bool
+	// TrailingCommas 允许对象和数组的最后一个元素之后出现逗号
+	TrailingCommas bool
+	// JSON5 打开 JSON5 的全部扩展，包括注释、尾随逗号、单引号字符串、标识符形式的键、
+	// 十六进制数、 Infinity 和 NaN 等
+	JSON5 bool
+	// MaxDepth 是对象和数组嵌套深度的上限，为 0 时使用 DefaultMaxDepth
+	MaxDepth int
+}
+
+// SyntaxError 是带有行列位置的语法错误，行列都从 1 开始，Offset 是以 rune 计的偏移
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("json: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Value 是按照 RFC 8259 解析一个 JSON 值的算子，值前后的空白会被跳过
+var Value = Parser(Options{})
+
+// parsers 缓存每一种 Options 构造的算子
+var parsers sync.Map
+
+// Parser 返回按照 opts 解析一个 JSON 值的算子，值前后的空白（以及允许的注释）会被跳过。
+// 相同的 opts 共用同一个算子，算子可以并发使用
+func Parser(opts Options) goP2.P {
+	opts = opts.normalize()
+	if p, ok := parsers.Load(opts); ok {
+		return p.(goP2.P)
+	}
+	p := newParser(opts)
+	body := p.ws.Then(p.value).Over(p.ws)
+	var re goP2.P = func(state goP2.State) (interface{}, error) {
+		return body(&nesting{State: state})
+	}
+	actual, _ := parsers.LoadOrStore(opts, re)
+	return actual.(goP2.P)
+}
+
+// Parse 按照 opts 解析完整的 JSON 文本，错误总是 *SyntaxError
+func Parse(text string, opts Options) (interface{}, error) {
+	state := goP2.BasicStateFromText(text)
+	re, err := Parser(opts).Over(goP2.EOF).Parse(&state)
+	if err != nil {
+		return nil, syntaxError([]rune(text), err)
+	}
+	return re, nil
+}
+
+// consuming 是读取了出错的元素之后才报告的错误代码，这些错误的位置在出错的元素之后
+var consuming = map[string]bool{
+	"eq": true, "ne": true, "one-of": true, "none-of": true,
+	"chr": true, "not-chr": true, "rune-of": true, "rune-none-of": true, "rune-pred": true,
+	"element-type": true,
+}
+
+func syntaxError(data []rune, err error) *SyntaxError {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return &SyntaxError{1, 1, 0, err.Error()}
+	}
+	pos := e.Pos
+	if consuming[e.Code] && pos > 0 {
+		pos--
+	}
+	at := goP2.LineCol(data, pos)
+	message := e.Message
+	if !consuming[e.Code] && pos >= len(data) {
+		message = "unexpected end of input: " + message
+	}
+	return &SyntaxError{at.Line, at.Column, pos, message}
+}
+
+// normalize 展开 JSON5 蕴含的选项并填写缺省值
+func (opts Options) normalize() Options {
+	if opts.JSON5 {
+		opts.Comments = true
+		opts.TrailingCommas = true
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	return opts
+}
+
+// nesting 在一次解析之内记录对象和数组的嵌套深度，使得 parser 本身没有可变的状态
+type nesting struct {
+	goP2.State
+	depth int
+}
+
+// Unwrap 返回被包装的 State
+func (n *nesting) Unwrap() goP2.State {
+	return n.State
+}
+
+type parser struct {
+	opts  Options
+	ws    goP2.P
+	value goP2.P
+}
+
+func newParser(opts Options) *parser {
+	p := &parser{opts: opts}
+
+	space := goP2.RuneOf(" \t\n\r")
+	if opts.JSON5 {
+		space = goP2.RuneP("whitespace", func(r rune) bool {
+			return r == '\uFEFF' || unicode.IsSpace(r) || unicode.Is(unicode.Zs, r)
+		})
+	}
+	if opts.Comments {
+		lineComment := goP2.Str("//").Then(goP2.Skip(goP2.RuneNone("\n\r")))
+		p.ws = goP2.Skip(goP2.Choice(goP2.Try(space), goP2.Try(lineComment), blockComment))
+	} else {
+		p.ws = goP2.Skip(space)
+	}
+
+	numberFirst := "-0123456789"
+	stringFirst := []interface{}{'"'}
+	if opts.JSON5 {
+		numberFirst += "+.IN"
+		stringFirst = append(stringFirst, '\'')
+	}
+	numberElements := make([]interface{}, 0, len(numberFirst))
+	for _, r := range numberFirst {
+		numberElements = append(numberElements, r)
+	}
+	p.value = goP2.Dispatch(
+		goP2.WithFirst(p.object, goP2.FirstOf('{')),
+		goP2.WithFirst(p.array, goP2.FirstOf('[')),
+		goP2.WithFirst(p.string, goP2.FirstOf(stringFirst...)),
+		goP2.WithFirst(p.number, goP2.FirstOf(numberElements...)),
+		goP2.WithFirst(literal("true", true), goP2.FirstOf('t')),
+		goP2.WithFirst(literal("false", false), goP2.FirstOf('f')),
+		goP2.WithFirst(literal("null", nil), goP2.FirstOf('n')),
+	).P
+	return p
+}
+
+func literal(name string, value interface{}) goP2.P {
+	return goP2.Str(name).Then(goP2.Return(value))
+}
+
+var blockComment = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Str("/*").Exec(state)
+	for {
+		if _, err := goP2.Try(goP2.Str("*/")).Parse(state); err == nil {
+			return nil
+		}
+		if _, err := state.Next(); err != nil {
+			panic(state.Trap("unterminated comment"))
+		}
+	}
+})
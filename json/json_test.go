@@ -0,0 +1,260 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+const suite = "testdata/JSONTestSuite/test_parsing"
+
+func TestJSONTestSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(suite, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("Expect test files in %s", suite)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSuiteCase(t, filepath.Base(file), data)
+	}
+}
+
+// TestJSONTestSuiteGenerated 运行上游语料中由程序生成的大文件，它们没有收录在 testdata 中
+func TestJSONTestSuiteGenerated(t *testing.T) {
+	checkSuiteCase(t, "n_structure_100000_opening_arrays.json", []byte(strings.Repeat("[", 100000)))
+	checkSuiteCase(t, "i_structure_500_nested_arrays.json", []byte(strings.Repeat("[", 500)+strings.Repeat("]", 500)))
+}
+
+// checkSuiteCase 按照 JSONTestSuite 文件名的前缀检查一个用例
+func checkSuiteCase(t *testing.T, name string, data []byte) {
+	switch name[0] {
+	case 'y':
+		re, err := Parse(string(data), Options{})
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", name, err)
+			return
+		}
+		var expect interface{}
+		if err := stdjson.Unmarshal(data, &expect); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(re, expect) {
+			t.Errorf("%s: Expect %#v but %#v", name, expect, re)
+		}
+	case 'n':
+		if re, err := Parse(string(data), Options{}); err == nil {
+			t.Errorf("%s: Expect error but %#v", name, re)
+		}
+	case 'i':
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("%s: panic %v", name, r)
+			}
+		}()
+		Parse(string(data), Options{})
+	}
+}
+
+func TestValue(t *testing.T) {
+	state := goP2.BasicStateFromText(` {"a": [1, true, null]} tail`)
+	re, err := Value.Parse(&state)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := map[string]interface{}{"a": []interface{}{1.0, true, nil}}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %#v but %#v", expect, re)
+	}
+	if r, _ := state.Next(); r != 't' {
+		t.Fatalf("Expect stop before tail but next is %v", r)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Parse("{\n  \"a\": [1,\n    2,,\n  ]\n}", Options{})
+	e, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("Expect *SyntaxError but %#v", err)
+	}
+	if e.Line != 3 || e.Column != 7 {
+		t.Fatalf("Expect error at line 3, column 7 but %v", e)
+	}
+	if !strings.HasPrefix(e.Error(), "json: line 3, column 7: ") {
+		t.Fatalf("Expect message with position but %q", e.Error())
+	}
+}
+
+func TestSyntaxErrorElement(t *testing.T) {
+	for _, c := range []struct {
+		text   string
+		column int
+	}{
+		{"[1.]", 4},
+		{"[-]", 3},
+		{"[1e+]", 5},
+		{`"\x"`, 3},
+	} {
+		_, err := Parse(c.text, Options{})
+		e, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%s: Expect *SyntaxError but %#v", c.text, err)
+		}
+		if e.Line != 1 || e.Column != c.column || strings.Contains(e.Message, "end of input") {
+			t.Errorf("%s: Expect error at column %d but %v", c.text, c.column, e)
+		}
+	}
+}
+
+func TestSyntaxErrorEOF(t *testing.T) {
+	for _, text := range []string{`{"a": "b`, "[1.", "[-"} {
+		_, err := Parse(text, Options{})
+		e, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%s: Expect *SyntaxError but %#v", text, err)
+		}
+		if !strings.HasPrefix(e.Message, "unexpected end of input") || e.Offset != len(text) {
+			t.Fatalf("%s: Expect unexpected end of input but %v", text, e)
+		}
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	re, err := Parse(`[12345678901234567890, -1.5e3]`, Options{UseNumber: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := []interface{}{stdjson.Number("12345678901234567890"), stdjson.Number("-1.5e3")}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %#v but %#v", expect, re)
+	}
+}
+
+func TestComments(t *testing.T) {
+	text := "// head\n{\"a\": /* inline */ 1 // tail\n}"
+	if _, err := Parse(text, Options{}); err == nil {
+		t.Fatalf("Expect comments rejected by default")
+	}
+	re, err := Parse(text, Options{Comments: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if !reflect.DeepEqual(re, map[string]interface{}{"a": 1.0}) {
+		t.Fatalf("Expect {a: 1} but %#v", re)
+	}
+	if _, err := Parse("[1 /* open", Options{Comments: true}); err == nil {
+		t.Fatalf("Expect unterminated comment error")
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	text := `{"a": [1, 2,], "b": {},}`
+	if _, err := Parse(text, Options{}); err == nil {
+		t.Fatalf("Expect trailing commas rejected by default")
+	}
+	re, err := Parse(text, Options{TrailingCommas: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := map[string]interface{}{"a": []interface{}{1.0, 2.0}, "b": map[string]interface{}{}}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %#v but %#v", expect, re)
+	}
+	if _, err := Parse(`[1,,]`, Options{TrailingCommas: true}); err == nil {
+		t.Fatalf("Expect double comma rejected")
+	}
+}
+
+func TestJSON5(t *testing.T) {
+	text := `{
+  // comments
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  trailingComma: 'in objects', andIn: ['arrays',],
+  "backwardsCompatible": "with JSON",
+}`
+	re, err := Parse(text, Options{JSON5: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := map[string]interface{}{
+		"unquoted":            "and you can quote me on that",
+		"singleQuotes":        `I can use "double quotes" here`,
+		"lineBreaks":          `Look, Mom! No \n's!`,
+		"hexadecimal":         float64(0xdecaf),
+		"leadingDecimalPoint": .8675309,
+		"andTrailing":         8675309.0,
+		"positiveSign":        1.0,
+		"trailingComma":       "in objects",
+		"andIn":               []interface{}{"arrays"},
+		"backwardsCompatible": "with JSON",
+	}
+	if !reflect.DeepEqual(re, expect) {
+		t.Fatalf("Expect %#v but %#v", expect, re)
+	}
+}
+
+func TestJSON5Numbers(t *testing.T) {
+	re, err := Parse(`[Infinity, -Infinity, NaN, '\x41\v']`, Options{JSON5: true})
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	items := re.([]interface{})
+	if !math.IsInf(items[0].(float64), 1) || !math.IsInf(items[1].(float64), -1) || !math.IsNaN(items[2].(float64)) {
+		t.Fatalf("Expect Infinity, -Infinity and NaN but %v", items)
+	}
+	if items[3] != "A\v" {
+		t.Fatalf("Expect \"A\\v\" but %q", items[3])
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	text := strings.Repeat("[", 5) + strings.Repeat("]", 5)
+	if _, err := Parse(text, Options{MaxDepth: 5}); err != nil {
+		t.Fatalf("Expect depth 5 accepted but %v", err)
+	}
+	_, err := Parse(text, Options{MaxDepth: 4})
+	if err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("Expect max depth error but %v", err)
+	}
+	deep := strings.Repeat("[", DefaultMaxDepth+1) + strings.Repeat("]", DefaultMaxDepth+1)
+	if _, err := Parse(deep, Options{}); err == nil {
+		t.Fatalf("Expect default max depth exceeded")
+	}
+	// 缓存的算子在失败之后不会保留上一次解析的深度
+	if _, err := Parse(text, Options{MaxDepth: 5}); err != nil {
+		t.Fatalf("Expect depth 5 accepted again but %v", err)
+	}
+}
+
+func TestParserShared(t *testing.T) {
+	text := `{"a": [1, [2, [3]]], "b": "x"}`
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := Parse(text, Options{MaxDepth: 4})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Expect concurrent parses to succeed but %v", err)
+		}
+	}
+}
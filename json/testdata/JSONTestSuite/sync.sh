@@ -0,0 +1,13 @@
+#!/bin/sh
+# 从上游复制完整的 test_parsing 目录，并把所用的提交记录在 UPSTREAM 文件中。
+# 用法： sh sync.sh <JSONTestSuite 的提交>
+set -e
+ref=${1:?usage: sh sync.sh <upstream commit>}
+dir=$(cd "$(dirname "$0")" && pwd)
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+git clone -q https://github.com/nst/JSONTestSuite.git "$tmp"
+git -C "$tmp" checkout -q "$ref"
+rm -rf "$dir/test_parsing"
+cp -R "$tmp/test_parsing" "$dir/test_parsing"
+git -C "$tmp" rev-parse HEAD > "$dir/UPSTREAM"
@@ -0,0 +1,315 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// accept 在下一个字符属于 set 时消费并返回它，否则不消费输入
+func accept(state goP2.State, set string) (rune, bool) {
+	x, err := goP2.Try(goP2.RuneOf(set)).Parse(state)
+	if err != nil {
+		return 0, false
+	}
+	return x.(rune), true
+}
+
+func (p *parser) enter(state goP2.State) *nesting {
+	n := state.(*nesting)
+	n.depth++
+	if n.depth > p.opts.MaxDepth {
+		panic(state.Trap("exceeded max depth %d", p.opts.MaxDepth))
+	}
+	return n
+}
+
+func (n *nesting) leave() {
+	n.depth--
+}
+
+// elements 解析对象或者数组中以逗号分隔的元素，直到遇到 close
+func (p *parser) elements(state goP2.State, close rune, element func()) {
+	n := p.enter(state)
+	defer n.leave()
+	p.ws.Exec(state)
+	if _, ok := accept(state, string([]rune{close})); ok {
+		return
+	}
+	separators := "," + string([]rune{close})
+	for {
+		element()
+		p.ws.Exec(state)
+		if goP2.RuneOf(separators).Exec(state) == close {
+			return
+		}
+		p.ws.Exec(state)
+		if p.opts.TrailingCommas {
+			if _, ok := accept(state, string([]rune{close})); ok {
+				return
+			}
+		}
+	}
+}
+
+func (p *parser) object(state goP2.State) (interface{}, error) {
+	return goP2.Do(func(state goP2.State) interface{} {
+		goP2.Chr('{').Exec(state)
+		re := make(map[string]interface{})
+		p.elements(state, '}', func() {
+			key := p.key(state)
+			p.ws.Exec(state)
+			goP2.Chr(':').Exec(state)
+			p.ws.Exec(state)
+			re[key] = p.value.Exec(state)
+		})
+		return re
+	})(state)
+}
+
+func (p *parser) array(state goP2.State) (interface{}, error) {
+	return goP2.Do(func(state goP2.State) interface{} {
+		goP2.Chr('[').Exec(state)
+		re := make([]interface{}, 0)
+		p.elements(state, ']', func() {
+			re = append(re, p.value.Exec(state))
+		})
+		return re
+	})(state)
+}
+
+var identStart = goP2.RuneP("identifier", func(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r)
+})
+
+var identPart = goP2.RuneP("identifier", func(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) || r == '\u200C' || r == '\u200D'
+})
+
+// key 解析对象的键， JSON5 中的键还可以是标识符
+func (p *parser) key(state goP2.State) string {
+	if p.opts.JSON5 {
+		if head, err := goP2.Try(identStart).Parse(state); err == nil {
+			tail := goP2.Many(identPart).Exec(state)
+			return string([]rune{head.(rune)}) + goP2.ToString(tail)
+		}
+		return goP2.P(p.string).Exec(state).(string)
+	}
+	if _, ok := accept(state, "\""); !ok {
+		pos := state.Pos()
+		x, err := state.Next()
+		if err != nil {
+			panic(err)
+		}
+		fail(state, pos, "Expect a string key but '%v'", x)
+	}
+	return p.stringBody(state, '"')
+}
+
+func (p *parser) string(state goP2.State) (interface{}, error) {
+	return goP2.Do(func(state goP2.State) interface{} {
+		quotes := "\""
+		if p.opts.JSON5 {
+			quotes = "\"'"
+		}
+		q := goP2.RuneOf(quotes).Exec(state).(rune)
+		return p.stringBody(state, q)
+	})(state)
+}
+
+// fail 将 state 移回 pos 并在该位置报错，用于读取了出错的元素之后再报告的错误
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+func next(state goP2.State, message string) rune {
+	x, err := state.Next()
+	if err != nil {
+		panic(state.Trap("%s", message))
+	}
+	r, ok := x.(rune)
+	if !ok {
+		panic(state.Trap("Expect a rune but %v", x))
+	}
+	return r
+}
+
+// stringBody 解析起始引号之后的字符串内容，直到遇到结束引号 q
+func (p *parser) stringBody(state goP2.State, q rune) string {
+	var b strings.Builder
+	for {
+		pos := state.Pos()
+		r := next(state, "unterminated string")
+		switch {
+		case r == q:
+			return b.String()
+		case r == '\\':
+			p.escape(state, &b)
+		case p.opts.JSON5 && (r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029'):
+			if r == '\u2028' || r == '\u2029' {
+				b.WriteRune(r)
+				continue
+			}
+			fail(state, pos, "unescaped line terminator in string")
+		case !p.opts.JSON5 && r < 0x20:
+			fail(state, pos, "invalid character %U in string", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func hex(state goP2.State, n int) rune {
+	var v rune
+	for i := 0; i < n; i++ {
+		pos := state.Pos()
+		r := next(state, "unterminated escape")
+		d, err := strconv.ParseUint(string([]rune{r}), 16, 8)
+		if err != nil {
+			fail(state, pos, "invalid hex digit '%s' in escape", string([]rune{r}))
+		}
+		v = v*16 + rune(d)
+	}
+	return v
+}
+
+var simpleEscapes = map[rune]rune{
+	'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+}
+
+func (p *parser) escape(state goP2.State, b *strings.Builder) {
+	pos := state.Pos()
+	r := next(state, "unterminated escape")
+	if c, ok := simpleEscapes[r]; ok {
+		b.WriteRune(c)
+		return
+	}
+	if r == 'u' {
+		c := hex(state, 4)
+		if utf16.IsSurrogate(c) {
+			pos := state.Pos()
+			if _, err := goP2.Try(goP2.Str("\\u")).Parse(state); err == nil {
+				if low := hex(state, 4); utf16.DecodeRune(c, low) != unicode.ReplacementChar {
+					b.WriteRune(utf16.DecodeRune(c, low))
+					return
+				}
+				state.SeekTo(pos)
+			}
+			c = unicode.ReplacementChar
+		}
+		b.WriteRune(c)
+		return
+	}
+	if !p.opts.JSON5 {
+		fail(state, pos, "invalid escape '\\%s'", string([]rune{r}))
+	}
+	switch r {
+	case 'v':
+		b.WriteRune('\v')
+	case 'x':
+		b.WriteRune(hex(state, 2))
+	case '0':
+		if _, err := goP2.Ahead(goP2.RuneOf("0123456789")).Parse(state); err == nil {
+			fail(state, pos, "invalid escape '\\0' followed by a digit")
+		}
+		b.WriteRune(0)
+	case '\r':
+		accept(state, "\n")
+	case '\n', '\u2028', '\u2029':
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		fail(state, pos, "invalid escape '\\%s'", string([]rune{r}))
+	default:
+		b.WriteRune(r)
+	}
+}
+
+const digits = "0123456789"
+
+// scanDigits 消费连续的十进制数字并返回它们
+func scanDigits(state goP2.State) string {
+	return goP2.ToString(goP2.Many(goP2.RuneOf(digits)).Exec(state))
+}
+
+func (p *parser) number(state goP2.State) (interface{}, error) {
+	return goP2.Do(func(state goP2.State) interface{} {
+		var b strings.Builder
+		signs := "-"
+		if p.opts.JSON5 {
+			signs = "-+"
+		}
+		sign, signed := accept(state, signs)
+		if signed && sign == '-' {
+			b.WriteRune('-')
+		}
+		if p.opts.JSON5 {
+			if _, err := goP2.Try(goP2.Str("Infinity")).Parse(state); err == nil {
+				return math.Inf(map[bool]int{true: -1, false: 1}[sign == '-'])
+			}
+			if _, err := goP2.Try(goP2.Str("NaN")).Parse(state); err == nil {
+				return math.NaN()
+			}
+			if _, err := goP2.Try(goP2.Chr('0').Then(goP2.RuneOf("xX"))).Parse(state); err == nil {
+				hexDigits := goP2.Many1(goP2.RuneOf("0123456789abcdefABCDEF")).Exec(state)
+				v, err := strconv.ParseUint(goP2.ToString(hexDigits), 16, 64)
+				if err != nil {
+					panic(state.Trap("invalid hex number: %v", err))
+				}
+				b.WriteString(strconv.FormatUint(v, 10))
+				return p.convert(state, b.String())
+			}
+		}
+
+		integer := ""
+		if zero, ok := accept(state, "0"); ok {
+			integer = string([]rune{zero})
+		} else if p.opts.JSON5 {
+			integer = scanDigits(state)
+		} else {
+			integer = goP2.ToString(goP2.Many1(goP2.RuneOf(digits)).Exec(state))
+		}
+		fraction := ""
+		if _, ok := accept(state, "."); ok {
+			if p.opts.JSON5 {
+				fraction = scanDigits(state)
+			} else {
+				fraction = goP2.ToString(goP2.Many1(goP2.RuneOf(digits)).Exec(state))
+			}
+		}
+		if integer == "" && fraction == "" {
+			panic(state.Trap("Expect digits in number"))
+		}
+		if integer == "" {
+			integer = "0"
+		}
+		b.WriteString(integer)
+		if fraction != "" {
+			b.WriteString("." + fraction)
+		}
+		if e, ok := accept(state, "eE"); ok {
+			b.WriteRune(e)
+			if s, ok := accept(state, "+-"); ok {
+				b.WriteRune(s)
+			}
+			b.WriteString(goP2.ToString(goP2.Many1(goP2.RuneOf(digits)).Exec(state)))
+		}
+		return p.convert(state, b.String())
+	})(state)
+}
+
+func (p *parser) convert(state goP2.State, text string) interface{} {
+	if p.opts.UseNumber {
+		return stdjson.Number(text)
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		panic(state.Trap("number %s out of range", text))
+	}
+	return f
+}
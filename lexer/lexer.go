@@ -0,0 +1,298 @@
+// Package lexer 提供独立的词法分析阶段：用正则表达式或者 goP2 算子定义规则，把源文本切分为带有
+// 种类、文本和位置的 Token ，再由 TokenState 把 Token 序列作为 goP2 的 State 交给语法分析。
+//
+// 规则按照最长匹配选择，长度相同时先定义的规则优先，所以关键字应当定义在标识符之前。
+// 每个规则属于一个模式，分析过程维护一个模式栈，栈顶的模式决定当前使用的规则。规则可以在匹配之后用
+// Goto 替换栈顶、用 Push 压入或者用 Pop 弹出模式，用于字符串插值、模板和 heredoc 这类上下文相关的
+// 词法。需要由语法决定模式时，使用按需分析的 StreamState 和 PushMode 、 PopMode 算子。
+package lexer
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// DefaultMode 是初始模式的名字
+const DefaultMode = "default"
+
+// Span 是 Token 在源文本中的范围，以 rune 计的偏移， End 不包含在内
+type Span struct {
+	Start int
+	End   int
+}
+
+// Token 是词法分析的结果
+type Token struct {
+	Kind string
+	Text string
+	Span Span
+	// Pos 是 Token 开始处的行列位置
+	Pos goP2.SourcePos
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s %q", t.Kind, t.Text)
+}
+
+// SyntaxError 是带有行列位置的词法或者语法错误，行列都从 1 开始， Offset 是以 rune 计的偏移
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("lexer: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Rule 是一条词法规则
+type Rule struct {
+	kind  string
+	skip  bool
+	next  string
+	push  string
+	pop   bool
+	match func(s *source, pos int) int
+}
+
+// Skip 让规则匹配的文本被丢弃而不产生 Token ，用于空白和注释
+func (r *Rule) Skip() *Rule {
+	r.skip = true
+	return r
+}
+
+// Goto 让规则匹配之后将模式栈的栈顶替换为 mode 模式
+func (r *Rule) Goto(mode string) *Rule {
+	r.next = mode
+	return r
+}
+
+// Push 让规则匹配之后将 mode 模式压入模式栈
+func (r *Rule) Push(mode string) *Rule {
+	r.push = mode
+	return r
+}
+
+// Pop 让规则匹配之后弹出模式栈的栈顶，回到之前的模式
+func (r *Rule) Pop() *Rule {
+	r.pop = true
+	return r
+}
+
+// apply 返回规则匹配之后的模式栈。模式栈总是复制之后修改，所以可以被多个位置共享
+func (r *Rule) apply(stack []string) ([]string, bool) {
+	switch {
+	case r.next != "":
+		stack = append(append([]string{}, stack[:len(stack)-1]...), r.next)
+	case r.push != "":
+		stack = append(append([]string{}, stack...), r.push)
+	case r.pop:
+		if len(stack) == 1 {
+			return nil, false
+		}
+		stack = stack[:len(stack)-1]
+	}
+	return stack, true
+}
+
+// Mode 是一组词法规则
+type Mode struct {
+	name  string
+	rules []*Rule
+}
+
+func (m *Mode) add(kind string, match func(s *source, pos int) int) *Rule {
+	r := &Rule{kind: kind, match: match}
+	m.rules = append(m.rules, r)
+	return r
+}
+
+// Regex 添加一条用正则表达式匹配的规则，表达式总是从当前位置开始匹配。 pattern 无效时 panic
+func (m *Mode) Regex(kind, pattern string) *Rule {
+	re := regexp.MustCompile(`^(?:` + pattern + `)`)
+	return m.add(kind, func(s *source, pos int) int {
+		loc := re.FindStringIndex(s.text[s.offsets[pos]:])
+		if loc == nil {
+			return -1
+		}
+		return utf8.RuneCountInString(s.text[s.offsets[pos] : s.offsets[pos]+loc[1]])
+	})
+}
+
+// Literal 添加一条匹配固定文本的规则
+func (m *Mode) Literal(kind, text string) *Rule {
+	return m.Regex(kind, regexp.QuoteMeta(text))
+}
+
+// Parser 添加一条用 goP2 算子匹配的规则，算子在源文本的 rune 序列上运行，消费的部分就是 Token 的文本
+func (m *Mode) Parser(kind string, p goP2.P) *Rule {
+	return m.add(kind, func(s *source, pos int) int {
+		s.state.SeekTo(pos)
+		if _, err := p.Parse(&s.state); err != nil {
+			return -1
+		}
+		return s.state.Pos() - pos
+	})
+}
+
+// Lexer 是由若干模式组成的词法分析器
+type Lexer struct {
+	modes map[string]*Mode
+}
+
+// New 构造一个新的 Lexer
+func New() *Lexer {
+	return &Lexer{map[string]*Mode{DefaultMode: {name: DefaultMode}}}
+}
+
+// Regex 向 DefaultMode 模式添加一条正则表达式规则
+func (l *Lexer) Regex(kind, pattern string) *Rule {
+	return l.Mode(DefaultMode).Regex(kind, pattern)
+}
+
+// Literal 向 DefaultMode 模式添加一条固定文本规则
+func (l *Lexer) Literal(kind, text string) *Rule {
+	return l.Mode(DefaultMode).Literal(kind, text)
+}
+
+// Parser 向 DefaultMode 模式添加一条 goP2 算子规则
+func (l *Lexer) Parser(kind string, p goP2.P) *Rule {
+	return l.Mode(DefaultMode).Parser(kind, p)
+}
+
+// Mode 返回名为 name 的模式，不存在时创建它
+func (l *Lexer) Mode(name string) *Mode {
+	m, ok := l.modes[name]
+	if !ok {
+		m = &Mode{name: name}
+		l.modes[name] = m
+	}
+	return m
+}
+
+// source 是正在分析的源文本
+type source struct {
+	text  string
+	runes []rune
+	// offsets[i] 是第 i 个 rune 的字节偏移，最后一项是文本的长度
+	offsets []int
+	state   goP2.BasicState
+}
+
+func newSource(text string) *source {
+	runes := []rune(text)
+	offsets := make([]int, 0, len(runes)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	return &source{text, runes, offsets, goP2.BasicStateFromText(text)}
+}
+
+// longest 在 mode 的规则中选择最长的匹配，没有匹配时返回 nil
+func (m *Mode) longest(s *source, pos int) (*Rule, int) {
+	var best *Rule
+	length := 0
+	for _, r := range m.rules {
+		if n := r.match(s, pos); n > length {
+			best, length = r, n
+		}
+	}
+	return best, length
+}
+
+// cursor 是词法分析的进度
+type cursor struct {
+	pos   int
+	at    goP2.SourcePos
+	stack []string
+}
+
+// start 返回源文本开头的进度，模式栈中只有 DefaultMode
+func start() cursor {
+	return cursor{0, goP2.SourcePos{Line: 1, Column: 1}, []string{DefaultMode}}
+}
+
+// lex 从 c 开始读取下一个 Token ，跳过被 Skip 的规则匹配的文本。到达结尾时返回 nil ，错误总是 *SyntaxError
+func (l *Lexer) lex(s *source, c cursor) (*Token, cursor, error) {
+	for c.pos < len(s.runes) {
+		mode := l.Mode(c.stack[len(c.stack)-1])
+		rule, n := mode.longest(s, c.pos)
+		if rule == nil {
+			return nil, c, &SyntaxError{c.at.Line, c.at.Column, c.pos,
+				fmt.Sprintf("unexpected character %q in mode %s", s.runes[c.pos], mode.name)}
+		}
+		stack, ok := rule.apply(c.stack)
+		if !ok {
+			return nil, c, &SyntaxError{c.at.Line, c.at.Column, c.pos,
+				fmt.Sprintf("%s pops the last mode %s", rule.kind, mode.name)}
+		}
+		runes := s.runes[c.pos : c.pos+n]
+		tok := Token{rule.kind, string(runes), Span{c.pos, c.pos + n}, c.at}
+		c = cursor{c.pos + n, c.at.Advance(runes), stack}
+		if !rule.skip {
+			return &tok, c, nil
+		}
+	}
+	return nil, c, nil
+}
+
+// Tokenize 将 text 切分为 Token 序列，被 Skip 的规则匹配的文本不产生 Token ，错误总是 *SyntaxError
+func (l *Lexer) Tokenize(text string) ([]Token, error) {
+	s := newSource(text)
+	re := []Token{}
+	for c := start(); ; {
+		tok, next, err := l.lex(s, c)
+		if err != nil {
+			return nil, err
+		}
+		if tok == nil {
+			return re, nil
+		}
+		re = append(re, *tok)
+		c = next
+	}
+}
+
+// State 将 text 切分为 Token 并构造 TokenState
+func (l *Lexer) State(text string) (*TokenState, error) {
+	tokens, err := l.Tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(text)
+	return NewTokenState(tokens, goP2.LineCol(runes, len(runes)), len(runes)), nil
+}
+
+// Parse 将 text 切分为 Token ，再用 p 解析全部的 Token ，错误总是 *SyntaxError
+func (l *Lexer) Parse(text string, p goP2.P) (interface{}, error) {
+	state, err := l.State(text)
+	if err != nil {
+		return nil, err
+	}
+	re, err := p.Over(End).Parse(state)
+	if err != nil {
+		return nil, state.Error(err)
+	}
+	return re, nil
+}
+
+// Stream 构造按需分析 text 的 StreamState
+func (l *Lexer) Stream(text string) *StreamState {
+	return newStreamState(l, text)
+}
+
+// ParseStream 用 p 解析 text ， Token 在解析过程中按需切分，所以 p 可以用 PushMode 和 PopMode
+// 决定后续文本的模式。错误总是 *SyntaxError
+func (l *Lexer) ParseStream(text string, p goP2.P) (interface{}, error) {
+	state := l.Stream(text)
+	re, err := p.Over(End).Parse(state)
+	if err != nil {
+		return nil, state.Error(err)
+	}
+	return re, nil
+}
@@ -0,0 +1,132 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func newCalc() *Lexer {
+	l := New()
+	l.Regex("space", `\s+`).Skip()
+	l.Regex("comment", `#[^\n]*`).Skip()
+	l.Literal("let", "let")
+	l.Regex("ident", `[a-z]+`)
+	l.Parser("number", goP2.Many1(goP2.Digit))
+	l.Regex("op", `[-+*/=]`)
+	l.Literal("quote", `"`).Goto("string")
+	l.Mode("string").Regex("text", `[^"]+`)
+	l.Mode("string").Literal("quote", `"`).Goto(DefaultMode)
+	return l
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := newCalc().Tokenize("let letter = 12 # note\n+ \"a b\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []string{}
+	for _, tok := range tokens {
+		kinds = append(kinds, tok.Kind+":"+tok.Text)
+	}
+	expect := []string{"let:let", "ident:letter", "op:=", "number:12", "op:+",
+		"quote:\"", "text:a b", "quote:\""}
+	if !reflect.DeepEqual(kinds, expect) {
+		t.Fatalf("Expect %v but %v", expect, kinds)
+	}
+	plus := tokens[4]
+	if plus.Pos != (goP2.SourcePos{Line: 2, Column: 1}) || plus.Span != (Span{23, 24}) {
+		t.Fatalf("Expect + at 2:1 [23,24) but %v %v", plus.Pos, plus.Span)
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	_, err := newCalc().Tokenize("let x\n  = $")
+	e, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("Expect *SyntaxError but %v", err)
+	}
+	if e.Line != 2 || e.Column != 5 || e.Offset != 10 {
+		t.Fatalf("Expect 2:5 at 10 but %d:%d at %d", e.Line, e.Column, e.Offset)
+	}
+	if !strings.Contains(e.Error(), `unexpected character '$' in mode default`) {
+		t.Fatalf("unexpected message %q", e.Error())
+	}
+}
+
+var assign = TokText("let", "let").Then(Tok("ident")).Bind(func(name interface{}) goP2.P {
+	return TokText("op", "=").Then(Tok("number")).Bind(func(value interface{}) goP2.P {
+		return goP2.Return(name.(Token).Text + "=" + value.(Token).Text)
+	})
+})
+
+func TestParse(t *testing.T) {
+	l := newCalc()
+	re, err := l.Parse("let x = 42", assign)
+	if err != nil || re != "x=42" {
+		t.Fatalf("Expect x=42 but %v, %v", re, err)
+	}
+
+	cases := []struct {
+		text    string
+		line    int
+		column  int
+		message string
+	}{
+		{"let x\n  + 42", 2, 3, `Expect op "=" but op "+"`},
+		{"let x =", 1, 8, `Expect number but end of input`},
+		{"let x = 1 y", 1, 11, `Expect end of input but ident "y"`},
+	}
+	for _, c := range cases {
+		_, err := l.Parse(c.text, assign)
+		e, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%q: expect *SyntaxError but %v", c.text, err)
+		}
+		if e.Line != c.line || e.Column != c.column || e.Message != c.message {
+			t.Fatalf("%q: expect %d:%d %s but %d:%d %s", c.text, c.line, c.column, c.message,
+				e.Line, e.Column, e.Message)
+		}
+	}
+}
+
+func TestTokenStateBacktrack(t *testing.T) {
+	state, err := newCalc().State("a + b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := goP2.Choice(goP2.Try(Tok("ident").Then(Tok("number"))), Tok("ident").Then(Tok("op")))
+	re, err := p.Parse(state)
+	if err != nil || re.(Token).Text != "+" {
+		t.Fatalf("Expect + but %v, %v", re, err)
+	}
+	if state.Pos() != 2 {
+		t.Fatalf("Expect position 2 but %d", state.Pos())
+	}
+}
+
+func TestTokenStateSpan(t *testing.T) {
+	state, err := newCalc().State("let a\n  = 12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Tok("let").Then(goP2.WithSpan(Tok("ident").Then(Tok("op"))))
+	re, err := p.Parse(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := re.(goP2.Spanned).Span
+	expect := goP2.Span{Start: 1, End: 3, From: goP2.SourcePos{Line: 1, Column: 5}, To: goP2.SourcePos{Line: 2, Column: 5}}
+	if span != expect {
+		t.Fatalf("Expect %v but %v", expect, span)
+	}
+	re, err = goP2.WithSpan(Tok("number")).Parse(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := re.(goP2.Spanned).Span.To; to != (goP2.SourcePos{Line: 2, Column: 7}) {
+		t.Fatalf("Expect the end of input 2:7 but %v", to)
+	}
+}
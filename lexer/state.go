@@ -0,0 +1,153 @@
+package lexer
+
+import (
+	"fmt"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// TokenState 是以 Token 为元素的 goP2 State ，位置是 Token 的下标
+type TokenState struct {
+	tokens []Token
+	index  int
+	begin  int
+	// 输入结尾的位置，用于报告 end of input 错误
+	endPos    goP2.SourcePos
+	endOffset int
+}
+
+// NewTokenState 用 Token 序列构造 TokenState ， end 和 endOffset 是输入结尾的行列位置和偏移
+func NewTokenState(tokens []Token, end goP2.SourcePos, endOffset int) *TokenState {
+	return &TokenState{tokens, 0, -1, end, endOffset}
+}
+
+// Tokens 返回全部的 Token
+func (s *TokenState) Tokens() []Token {
+	return s.tokens
+}
+
+// Pos 返回当前 Token 的下标
+func (s *TokenState) Pos() int {
+	return s.index
+}
+
+// Position 返回当前 Token 开始处的行列位置，在结尾时是输入结尾的位置。
+// goP2.WithSpan 用它填写 Span 的 From 和 To
+func (s *TokenState) Position() goP2.SourcePos {
+	if s.index < len(s.tokens) {
+		return s.tokens[s.index].Pos
+	}
+	return s.endPos
+}
+
+// SeekTo 将位置移动到 pos ，允许移动到结尾
+func (s *TokenState) SeekTo(pos int) bool {
+	if 0 <= pos && pos <= len(s.tokens) {
+		s.index = pos
+		return true
+	}
+	return false
+}
+
+// Next 返回下一个 Token
+func (s *TokenState) Next() (interface{}, error) {
+	if s.index == len(s.tokens) {
+		return nil, s.Trap("unexpected end of input")
+	}
+	re := s.tokens[s.index]
+	s.index++
+	return re, nil
+}
+
+// Trap 构造当前位置的错误
+func (s *TokenState) Trap(message string, args ...interface{}) error {
+	return goP2.Error{Pos: s.index, Message: fmt.Sprintf(message, args...)}
+}
+
+// Begin 开始一个事务并返回事务号
+func (s *TokenState) Begin() int {
+	if s.begin == -1 {
+		s.begin = s.index
+	}
+	return s.index
+}
+
+// Commit 提交一个事务
+func (s *TokenState) Commit(tran int) {
+	if s.begin == tran {
+		s.begin = -1
+	}
+}
+
+// Rollback 取消一个事务，将位置移回事务开始处
+func (s *TokenState) Rollback(tran int) {
+	s.SeekTo(tran)
+	if s.begin == tran {
+		s.begin = -1
+	}
+}
+
+// Error 将解析错误转换为带有行列位置的 *SyntaxError ，位置是出错的 Token 的开始处
+func (s *TokenState) Error(err error) error {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return err
+	}
+	if e.Pos < len(s.tokens) {
+		t := s.tokens[e.Pos]
+		return &SyntaxError{t.Pos.Line, t.Pos.Column, t.Span.Start, e.Message}
+	}
+	return &SyntaxError{s.endPos.Line, s.endPos.Column, s.endOffset, e.Message}
+}
+
+// describe 描述位置 pos 处的 Token ，用于错误信息
+func (s *TokenState) describe(pos int) string {
+	if pos >= len(s.tokens) {
+		return "end of input"
+	}
+	return s.tokens[pos].String()
+}
+
+// expect 读取一个满足 pred 的 Token ，失败时不消费输入，错误指向不满足的 Token
+func expect(name string, pred func(Token) bool) goP2.P {
+	return func(state goP2.State) (interface{}, error) {
+		pos := state.Pos()
+		x, err := state.Next()
+		if err == nil {
+			if t, ok := x.(Token); ok && pred(t) {
+				return t, nil
+			}
+		}
+		state.SeekTo(pos)
+		found := "end of input"
+		if s, ok := state.(interface{ describe(int) string }); ok {
+			found = s.describe(pos)
+		} else if err == nil {
+			found = fmt.Sprint(x)
+		}
+		return nil, state.Trap("Expect %s but %s", name, found)
+	}
+}
+
+// Tok 匹配一个 kind 种类的 Token ，结果为 Token 。失败时不消费输入
+func Tok(kind string) goP2.P {
+	return expect(kind, func(t Token) bool { return t.Kind == kind })
+}
+
+// TokText 匹配一个 kind 种类并且文本为 text 的 Token ，结果为 Token 。失败时不消费输入
+func TokText(kind, text string) goP2.P {
+	return expect(fmt.Sprintf("%s %q", kind, text), func(t Token) bool {
+		return t.Kind == kind && t.Text == text
+	})
+}
+
+// End 在没有剩余的 Token 时成功，否则在剩余的第一个 Token 处报错
+func End(state goP2.State) (interface{}, error) {
+	pos := state.Pos()
+	x, err := state.Next()
+	if err != nil {
+		return nil, nil
+	}
+	state.SeekTo(pos)
+	return nil, state.Trap("Expect end of input but %v", x)
+}
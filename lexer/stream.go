@@ -0,0 +1,236 @@
+package lexer
+
+import (
+	"fmt"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// StreamState 是在解析过程中按需切分 Token 的 goP2 State ，位置是 Token 的下标。
+// 它维护当前位置的模式栈， PushMode 和 PopMode 修改模式栈之后，后续的 Token 按照新的模式切分；
+// 已经切分的 Token 如果是在不同的模式栈下得到的，会被丢弃并重新切分。
+// 模式栈在 Begin 时保存快照，在 Rollback 时恢复，所以 Try 回溯之后模式也会回到之前的状态。
+type StreamState struct {
+	lexer *Lexer
+	src   *source
+	// 已经切分的 Token
+	buf   []entry
+	index int
+	stack []string
+	// tail 描述 buf 之后的内容：输入的结尾或者词法错误，未知时为 nil
+	tail      *SyntaxError
+	snapshots []modeSnapshot
+}
+
+// entry 是一个已经切分的 Token ， before 是切分时的模式栈， after 是切分之后的进度
+type entry struct {
+	token  Token
+	before []string
+	after  cursor
+}
+
+type modeSnapshot struct {
+	tran  int
+	stack []string
+}
+
+func newStreamState(l *Lexer, text string) *StreamState {
+	return &StreamState{lexer: l, src: newSource(text), stack: start().stack}
+}
+
+// Modes 返回当前的模式栈，栈顶在最后
+func (s *StreamState) Modes() []string {
+	return append([]string{}, s.stack...)
+}
+
+// Pos 返回当前 Token 的下标
+func (s *StreamState) Pos() int {
+	return s.index
+}
+
+// Position 返回当前 Token 开始处的行列位置，在结尾时是输入结尾或者词法错误的位置。
+// 当前 Token 还没有切分时按照当前的模式栈预先切分，位置不变。 goP2.WithSpan 用它填写 Span 的 From 和 To
+func (s *StreamState) Position() goP2.SourcePos {
+	pos := s.index
+	if _, err := s.Next(); err == nil {
+		s.SeekTo(pos)
+		return s.buf[pos].token.Pos
+	}
+	return goP2.SourcePos{Line: s.tail.Line, Column: s.tail.Column}
+}
+
+// SeekTo 将位置移动到已经切分的 pos 处，模式栈也恢复为该位置的模式栈
+func (s *StreamState) SeekTo(pos int) bool {
+	if pos < 0 || pos > len(s.buf) {
+		return false
+	}
+	switch {
+	case pos < len(s.buf):
+		s.stack = s.buf[pos].before
+	case pos == s.index:
+	case pos > 0:
+		s.stack = s.buf[pos-1].after.stack
+	default:
+		s.stack = start().stack
+	}
+	s.index = pos
+	return true
+}
+
+// cursor 返回当前位置的分析进度
+func (s *StreamState) cursor() cursor {
+	c := start()
+	if s.index > 0 {
+		c = s.buf[s.index-1].after
+	}
+	c.stack = s.stack
+	return c
+}
+
+// Next 返回下一个 Token ，必要时按照当前的模式栈切分
+func (s *StreamState) Next() (interface{}, error) {
+	if s.index < len(s.buf) && sameModes(s.buf[s.index].before, s.stack) {
+		e := s.buf[s.index]
+		s.index++
+		s.stack = e.after.stack
+		return e.token, nil
+	}
+	if s.index < len(s.buf) {
+		s.buf, s.tail = s.buf[:s.index], nil
+	}
+	c := s.cursor()
+	tok, next, err := s.lexer.lex(s.src, c)
+	if err != nil {
+		s.tail = err.(*SyntaxError)
+		return nil, s.Trap("%s", s.tail.Message)
+	}
+	if tok == nil {
+		s.tail = &SyntaxError{next.at.Line, next.at.Column, next.pos, "end of input"}
+		return nil, s.Trap("unexpected end of input")
+	}
+	s.buf = append(s.buf, entry{*tok, s.stack, next})
+	s.tail = nil
+	s.index++
+	s.stack = next.stack
+	return *tok, nil
+}
+
+func sameModes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Trap 构造当前位置的错误
+func (s *StreamState) Trap(message string, args ...interface{}) error {
+	return goP2.Error{Pos: s.index, Message: fmt.Sprintf(message, args...)}
+}
+
+// Begin 开始一个事务并保存模式栈的快照
+func (s *StreamState) Begin() int {
+	s.snapshots = append(s.snapshots, modeSnapshot{s.index, s.stack})
+	return s.index
+}
+
+// pop 弹出 tran 对应的快照，同一位置上可能有多个事务，所以从栈顶开始查找
+func (s *StreamState) pop(tran int) (modeSnapshot, bool) {
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i].tran == tran {
+			snap := s.snapshots[i]
+			s.snapshots = s.snapshots[:i]
+			return snap, true
+		}
+	}
+	return modeSnapshot{}, false
+}
+
+// Commit 提交一个事务，丢弃对应的快照
+func (s *StreamState) Commit(tran int) {
+	s.pop(tran)
+}
+
+// Rollback 取消一个事务，将位置移回事务开始处并恢复模式栈
+func (s *StreamState) Rollback(tran int) {
+	s.SeekTo(tran)
+	if snap, ok := s.pop(tran); ok {
+		s.stack = snap.stack
+	}
+}
+
+// Error 将解析错误转换为带有行列位置的 *SyntaxError ，位置是出错的 Token 的开始处，
+// 在已经切分的 Token 之后时是词法错误或者输入结尾的位置
+func (s *StreamState) Error(err error) error {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return err
+	}
+	if e.Pos < len(s.buf) {
+		t := s.buf[e.Pos].token
+		return &SyntaxError{t.Pos.Line, t.Pos.Column, t.Span.Start, e.Message}
+	}
+	if s.tail != nil {
+		return &SyntaxError{s.tail.Line, s.tail.Column, s.tail.Offset, e.Message}
+	}
+	c := start()
+	if len(s.buf) > 0 {
+		c = s.buf[len(s.buf)-1].after
+	}
+	return &SyntaxError{c.at.Line, c.at.Column, c.pos, e.Message}
+}
+
+// describe 描述位置 pos 处的 Token ，用于错误信息
+func (s *StreamState) describe(pos int) string {
+	if pos < len(s.buf) {
+		return s.buf[pos].token.String()
+	}
+	if s.tail != nil {
+		return s.tail.Message
+	}
+	return "end of input"
+}
+
+// findStreamState 沿着 Unwrap 链查找 StreamState
+func findStreamState(state goP2.State) (*StreamState, error) {
+	for s := state; ; {
+		if u, ok := s.(*StreamState); ok {
+			return u, nil
+		}
+		w, ok := s.(interface{ Unwrap() goP2.State })
+		if !ok {
+			return nil, state.Trap("mode combinators require a StreamState but %T", state)
+		}
+		s = w.Unwrap()
+	}
+}
+
+// PushMode 返回将 mode 模式压入模式栈的算子，不消费输入，结果为 nil
+func PushMode(mode string) goP2.P {
+	return func(state goP2.State) (interface{}, error) {
+		s, err := findStreamState(state)
+		if err != nil {
+			return nil, err
+		}
+		s.stack = append(append([]string{}, s.stack...), mode)
+		return nil, nil
+	}
+}
+
+// PopMode 弹出模式栈的栈顶，不消费输入，结果为弹出的模式。模式栈中只剩一个模式时失败
+func PopMode(state goP2.State) (interface{}, error) {
+	s, err := findStreamState(state)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.stack) == 1 {
+		return nil, state.Trap("cannot pop the last mode %s", s.stack[0])
+	}
+	top := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	return top, nil
+}
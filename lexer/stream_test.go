@@ -0,0 +1,109 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// newTemplate 构造支持 "${ ... }" 插值的字符串词法，插值中可以嵌套字符串
+func newTemplate() *Lexer {
+	l := New()
+	l.Regex("space", `\s+`).Skip()
+	l.Regex("ident", `[a-z]+`)
+	l.Regex("op", `[-+*/]`)
+	l.Literal("quote", `"`).Push("string")
+	l.Literal("rbrace", "}").Pop()
+	str := l.Mode("string")
+	str.Regex("text", `([^"$]|\$[^{"])+`)
+	str.Literal("interp", "${").Push(DefaultMode)
+	str.Literal("quote", `"`).Pop()
+	return l
+}
+
+func TestTokenizeModeStack(t *testing.T) {
+	tokens, err := newTemplate().Tokenize(`"a ${x + "b${y}"} c" z`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []string{}
+	for _, tok := range tokens {
+		kinds = append(kinds, tok.Kind+":"+tok.Text)
+	}
+	expect := []string{`quote:"`, "text:a ", "interp:${", "ident:x", "op:+", `quote:"`, "text:b",
+		"interp:${", "ident:y", "rbrace:}", `quote:"`, "rbrace:}", "text: c", `quote:"`, "ident:z"}
+	if !reflect.DeepEqual(kinds, expect) {
+		t.Fatalf("Expect %v but %v", expect, kinds)
+	}
+
+	_, err = newTemplate().Tokenize("x }")
+	if e, ok := err.(*SyntaxError); !ok || e.Column != 3 || e.Message != "rbrace pops the last mode default" {
+		t.Fatalf("Expect underflow error at column 3 but %v", err)
+	}
+}
+
+// newRaw 构造由语法决定模式的词法： raw 之后的花括号内容是原始文本
+func newRaw() *Lexer {
+	l := New()
+	l.Regex("space", `\s+`).Skip()
+	l.Regex("ident", `[a-z]+`)
+	l.Literal("lbrace", "{")
+	l.Literal("rbrace", "}")
+	l.Mode("raw").Regex("space", `\s+`).Skip()
+	l.Mode("raw").Regex("raw", `\{[^}]*\}`).Pop()
+	return l
+}
+
+func TestStreamPushMode(t *testing.T) {
+	l := newRaw()
+	raw := TokText("ident", "raw").Then(PushMode("raw")).Then(Tok("raw"))
+	p := raw.Then(Tok("ident"))
+	re, err := l.ParseStream("raw { $% } end", p)
+	if err != nil || re.(Token).Text != "end" {
+		t.Fatalf("Expect end but %v, %v", re, err)
+	}
+
+	// 没有 PushMode 时 "$" 是词法错误，报告在解析到它的时候
+	_, err = l.ParseStream("raw { $ }", TokText("ident", "raw").Then(Tok("lbrace")).Then(Tok("ident")))
+	e, ok := err.(*SyntaxError)
+	if !ok || e.Column != 7 || e.Message != `Expect ident but unexpected character '$' in mode default` {
+		t.Fatalf("Expect lexical error at column 7 but %v", err)
+	}
+}
+
+func TestStreamBacktrack(t *testing.T) {
+	state := newRaw().Stream("{a} b")
+	// 第一个分支以 raw 模式切分了 "{a}" 之后失败，回溯之后模式栈恢复， Token 按照默认模式重新切分
+	first := goP2.Try(PushMode("raw").Then(Tok("raw")).Then(Tok("lbrace")))
+	second := Tok("lbrace").Then(Tok("ident")).Then(Tok("rbrace")).Then(Tok("ident"))
+	re, err := goP2.Choice(first, second).Parse(state)
+	if err != nil || re.(Token).Text != "b" {
+		t.Fatalf("Expect b but %v, %v", state.Error(err), re)
+	}
+	if modes := state.Modes(); !reflect.DeepEqual(modes, []string{DefaultMode}) {
+		t.Fatalf("Expect [default] but %v", modes)
+	}
+
+	if _, err := goP2.P(PopMode).Parse(state); err == nil {
+		t.Fatalf("Expect error when popping the last mode")
+	}
+	plain := goP2.BasicStateFromText("x")
+	if _, err := PushMode("raw").Parse(&plain); err == nil {
+		t.Fatalf("Expect error for a state without mode stack")
+	}
+}
+
+func TestStreamStateSpan(t *testing.T) {
+	state := newTemplate().Stream("a\n + b")
+	p := Tok("ident").Then(goP2.WithSpan(Tok("op").Then(Tok("ident"))))
+	re, err := p.Parse(state)
+	if err != nil {
+		t.Fatal(state.Error(err))
+	}
+	span := re.(goP2.Spanned).Span
+	expect := goP2.Span{Start: 1, End: 3, From: goP2.SourcePos{Line: 2, Column: 2}, To: goP2.SourcePos{Line: 2, Column: 5}}
+	if span != expect {
+		t.Fatalf("Expect %v but %v", expect, span)
+	}
+}
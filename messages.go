@@ -0,0 +1,166 @@
+package goP2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Catalog 是一种语言的错误信息目录，键是错误代码，值是 fmt 格式的模板，可以用 %[n]v 调整参数的顺序。
+// 参数中的 []string 用 "list.separator" 和 "list.or" 两项连接成 "a, b or c" 的形式，缺省为 ", " 和 " or "。
+// 自定义的错误代码只需要加入各个语言的目录中
+type Catalog map[string]string
+
+// English 是英文的错误信息目录， Raise 用它构造错误的 Message
+var English = Catalog{
+	"eof":             "eof",
+	"eof.expected":    "Expect eof but %v",
+	"eq":              "Expact %v but %v",
+	"ne":              "Expact not %v but %v",
+	"one-of":          "Expect one of [%v] but %v",
+	"none-of":         "Expect none of [%v] but %v",
+	"chr":             "Expect '%v' but '%v'",
+	"not-chr":         "Expect not '%v' but '%v'",
+	"rune-of":         "Expect rune in '%s' but '%s'",
+	"rune-none-of":    "Expect rune none of '%s' but '%s'",
+	"rune-pred":       "Expect %s but '%v'",
+	"expect":          "Expect %v but %v",
+	"expect.eof":      "Expect %v but end of input",
+	"element-type":    "Expect a %[1]s but %[2]v is %[2]T",
+	"sized":           "Expect %d elements but parsed %d",
+	"length":          "invalid length %v",
+	"varint.overflow": "varint overflows a 64-bit integer",
+	"indent.state":    "indentation combinators require an IndentState but %T",
+	"indent.column":   "Expect indentation at column %d but %d",
+	"indent.greater":  "Expect indentation greater than column %d but %d",
+}
+
+// Chinese 是中文的错误信息目录
+var Chinese = Catalog{
+	"list.separator":  "、",
+	"list.or":         " 或 ",
+	"eof":             "输入意外结束",
+	"eof.expected":    "期望输入结束，但是遇到了 %v",
+	"eq":              "期望 %v ，但是遇到了 %v",
+	"ne":              "期望不是 %v ，但是遇到了 %v",
+	"one-of":          "期望 [%v] 中的一个，但是遇到了 %v",
+	"none-of":         "期望不是 [%v] 中的任何一个，但是遇到了 %v",
+	"chr":             "期望 '%v' ，但是遇到了 '%v'",
+	"not-chr":         "期望不是 '%v' ，但是遇到了 '%v'",
+	"rune-of":         "期望 '%s' 中的字符，但是遇到了 '%s'",
+	"rune-none-of":    "期望不在 '%s' 中的字符，但是遇到了 '%s'",
+	"rune-pred":       "期望 %s ，但是遇到了 '%v'",
+	"expect":          "期望 %v ，但是遇到了 %v",
+	"expect.eof":      "期望 %v ，但是输入已经结束",
+	"element-type":    "期望 %[1]s ，但是遇到了 %[2]T 类型的 %[2]v",
+	"sized":           "期望解析 %d 个元素，但是解析了 %d 个",
+	"length":          "无效的长度 %v",
+	"varint.overflow": "varint 超出了 64 位整数的范围",
+	"indent.state":    "缩进算子需要 IndentState ，但是遇到了 %T",
+	"indent.column":   "期望缩进在第 %d 列，但是在第 %d 列",
+	"indent.greater":  "期望缩进大于第 %d 列，但是在第 %d 列",
+}
+
+// Japanese 是日文的错误信息目录
+var Japanese = Catalog{
+	"list.separator":  "、",
+	"list.or":         " または ",
+	"eof":             "予期しない入力の終わりです",
+	"eof.expected":    "入力の終わりが必要ですが、%v がありました",
+	"eq":              "%[1]v が必要ですが、%[2]v がありました",
+	"ne":              "%[1]v 以外が必要ですが、%[2]v がありました",
+	"one-of":          "[%[1]v] のいずれかが必要ですが、%[2]v がありました",
+	"none-of":         "[%[1]v] 以外が必要ですが、%[2]v がありました",
+	"chr":             "'%[1]v' が必要ですが、'%[2]v' がありました",
+	"not-chr":         "'%[1]v' 以外が必要ですが、'%[2]v' がありました",
+	"rune-of":         "'%[1]s' のいずれかの文字が必要ですが、'%[2]s' がありました",
+	"rune-none-of":    "'%[1]s' 以外の文字が必要ですが、'%[2]s' がありました",
+	"rune-pred":       "%[1]s が必要ですが、'%[2]v' がありました",
+	"expect":          "%[1]v が必要ですが、%[2]v がありました",
+	"expect.eof":      "%v が必要ですが、入力が終わりました",
+	"element-type":    "%[1]s が必要ですが、%[2]T 型の %[2]v がありました",
+	"sized":           "%[1]d 個の要素が必要ですが、%[2]d 個を解析しました",
+	"length":          "無効な長さ %v です",
+	"varint.overflow": "varint が 64 ビット整数の範囲を超えています",
+	"indent.state":    "インデント演算子には IndentState が必要ですが、%T がありました",
+	"indent.column":   "%[1]d 列目のインデントが必要ですが、%[2]d 列目でした",
+	"indent.greater":  "%[1]d 列目より深いインデントが必要ですが、%[2]d 列目でした",
+}
+
+// join 按照目录的习惯连接列表
+func (c Catalog) join(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	sep, or := ", ", " or "
+	if s, ok := c["list.separator"]; ok {
+		sep = s
+	}
+	if s, ok := c["list.or"]; ok {
+		or = s
+	}
+	return strings.Join(items[:len(items)-1], sep) + or + items[len(items)-1]
+}
+
+// format 用 code 的模板格式化 args ，没有模板时返回 false
+func (c Catalog) format(code string, args []interface{}) (string, bool) {
+	template, ok := c[code]
+	if !ok {
+		return "", false
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if items, ok := arg.([]string); ok {
+			values[i] = c.join(items)
+		} else {
+			values[i] = arg
+		}
+	}
+	return fmt.Sprintf(template, values...), true
+}
+
+// Message 返回 err 在这种语言下的错误信息。没有错误代码或者目录中没有对应的模板时，
+// 返回原来的信息，所以用 Trap 构造的错误总是显示原来的信息
+func (c Catalog) Message(err error) string {
+	e, ok := err.(Error)
+	if !ok {
+		return err.Error()
+	}
+	if e.Code != "" {
+		if msg, ok := c.format(e.Code, e.Args); ok {
+			return msg
+		}
+	}
+	return e.Message
+}
+
+// Localize 返回信息替换为这种语言的错误，其它字段不变
+func (c Catalog) Localize(err error) error {
+	e, ok := err.(Error)
+	if !ok {
+		return err
+	}
+	e.Message = c.Message(e)
+	return e
+}
+
+// Raise 构造带有错误代码 code 和参数 args 的错误，信息由 English 中的模板格式化，
+// 没有模板时信息为错误代码和参数
+func Raise(state State, code string, args ...interface{}) error {
+	msg, ok := English.format(code, args)
+	if !ok {
+		msg = strings.TrimSpace(fmt.Sprintln(append([]interface{}{code}, args...)...))
+	}
+	err := state.Trap("%s", msg)
+	if e, ok := err.(Error); ok {
+		e.Code, e.Args = code, args
+		return e
+	}
+	return err
+}
+
+// FailWith 生成的算子总是返回错误代码为 code 的错误，参见 Raise
+func FailWith(code string, args ...interface{}) P {
+	return func(state State) (interface{}, error) {
+		return nil, Raise(state, code, args...)
+	}
+}
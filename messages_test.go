@@ -0,0 +1,92 @@
+package goP2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	state := BasicStateFromText("ab")
+	_, err := Str("ax").Parse(&state)
+	e := err.(Error)
+	if e.Code != "chr" || e.Message != "Expect 'x' but 'b'" {
+		t.Fatalf("Expect chr error but %#v", e)
+	}
+	if msg := Chinese.Message(err); msg != "期望 'x' ，但是遇到了 'b'" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if msg := Japanese.Message(err); msg != "'x' が必要ですが、'b' がありました" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	state = BasicStateFromText("")
+	_, err = Choice(Label(Try(Digit), "number"), Label(Try(Chr('"')), "string")).Parse(&state)
+	if msg := Chinese.Message(err); msg != "期望 number 或 string ，但是输入已经结束" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	localized := Japanese.Localize(err).(Error)
+	if localized.Message != "number または string が必要ですが、入力が終わりました" || localized.Code != "expect.eof" {
+		t.Fatalf("unexpected error %#v", localized)
+	}
+
+	// 用 Trap 构造的错误没有错误代码，保持原来的信息
+	_, err = Fail("custom %d", 1).Parse(&state)
+	if msg := Chinese.Message(err); msg != "custom 1" {
+		t.Fatalf("Expect fallback message but %q", msg)
+	}
+	if msg := Chinese.Message(errors.New("plain")); msg != "plain" {
+		t.Fatalf("Expect plain message but %q", msg)
+	}
+}
+
+func TestFailWith(t *testing.T) {
+	English["app.undefined"] = "undefined name %s"
+	Chinese["app.undefined"] = "名字 %s 没有定义"
+	defer delete(English, "app.undefined")
+	defer delete(Chinese, "app.undefined")
+	state := BasicStateFromText("x")
+	_, err := FailWith("app.undefined", "x").Parse(&state)
+	if err.(Error).Message != "undefined name x" || Chinese.Message(err) != "名字 x 没有定义" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	_, err = FailWith("app.unknown", 1, 2).Parse(&state)
+	if e := err.(Error); e.Message != "app.unknown 1 2" || Japanese.Message(err) != "app.unknown 1 2" {
+		t.Fatalf("unexpected error %#v", e)
+	}
+}
+
+func TestCatalogCodes(t *testing.T) {
+	bytes := BasicStateFromBytes([]byte("ab"))
+	_, err := Bytes("ax").Parse(&bytes)
+	if e := err.(Error); e.Code != "chr" || Chinese.Message(err) != "期望 'x' ，但是遇到了 'b'" {
+		t.Fatalf("Expect chr error but %#v", e)
+	}
+	text := BasicStateFromText("a")
+	_, err = Byte('a').Parse(&text)
+	if e := err.(Error); e.Code != "element-type" || e.Message != "Expect a byte but 97 is int32" {
+		t.Fatalf("Expect element-type error but %#v", e)
+	}
+
+	bytes = BasicStateFromBytes([]byte("abc"))
+	_, err = Sized(2, Take(1)).Parse(&bytes)
+	if e := err.(Error); e.Code != "sized" || Japanese.Message(err) != "2 個の要素が必要ですが、1 個を解析しました" {
+		t.Fatalf("Expect sized error but %#v", e)
+	}
+
+	text = BasicStateFromText("/")
+	_, err = Dispatch(FChr('+'), FChr('-')).Parse(&text)
+	if e := err.(Error); e.Code != "expect" || e.Message != "Expect '+' or '-' but '/'" || e.Pos != 0 {
+		t.Fatalf("Expect the FIRST set described but %#v", e)
+	}
+
+	text = BasicStateFromText("x")
+	_, err = WithPos(Indented).Parse(NewIndentState(&text))
+	if e := err.(Error); e.Code != "indent.greater" || Chinese.Message(err) != "期望缩进大于第 1 列，但是在第 1 列" {
+		t.Fatalf("Expect indent.greater error but %#v", e)
+	}
+	text = BasicStateFromText("x")
+	_, err = P(CheckIndent).Parse(&text)
+	if e := err.(Error); e.Code != "indent.state" {
+		t.Fatalf("Expect indent.state error but %#v", e)
+	}
+}
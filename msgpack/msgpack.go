@@ -0,0 +1,254 @@
+// Package msgpack 是基于 goP2 字节算子的 MessagePack 解码器，支持规范中的全部格式，
+// 包括 ext 类型和预定义的时间戳扩展。
+//
+// 解码结果对应的 Go 类型：nil 为 nil ， bool 为 bool ，整数为 int64 （超出 int64 的无符号整数为 uint64），
+// float 32 为 float32 ， float 64 为 float64 ， str 为 string ， bin 为 []byte ， array 为 []interface{} ，
+// map 为 map[interface{}]interface{} ，时间戳扩展为 time.Time ，其它扩展为 Ext 。
+package msgpack
+
+import (
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// DefaultMaxDepth 是 Options.MaxDepth 为 0 时使用的嵌套深度上限
+const DefaultMaxDepth = 1000
+
+// Options 控制解码的行为
+type Options struct {
+	// MaxDepth 是 array 和 map 嵌套深度的上限，为 0 时使用 DefaultMaxDepth
+	MaxDepth int
+}
+
+// Ext 是应用定义的扩展类型
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// TimestampType 是预定义的时间戳扩展类型
+const TimestampType = -1
+
+// ParseError 是解码错误， Offset 是字节偏移
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("msgpack: offset %d: %s", e.Offset, e.Message)
+}
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// Value 是解码一个 MessagePack 值的算子
+var Value = Parser(Options{})
+
+// Parser 返回按照 opts 解码一个 MessagePack 值的算子
+func Parser(opts Options) goP2.P {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	return goP2.Do(func(state goP2.State) interface{} {
+		d := &decoder{opts: opts}
+		return d.value(state)
+	})
+}
+
+// Decode 解码 data 中唯一的一个值，错误总是 *ParseError
+func Decode(data []byte, opts Options) (interface{}, error) {
+	state := goP2.BasicStateFromBytes(data)
+	re, err := Parser(opts).Over(end).Parse(&state)
+	if err != nil {
+		if e, ok := err.(goP2.Error); ok {
+			return nil, &ParseError{e.Pos, e.Message}
+		}
+		return nil, &ParseError{state.Pos(), err.Error()}
+	}
+	return re, nil
+}
+
+// end 在输入结束时成功，否则在剩余的第一个字节处报错
+var end = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	if _, err := state.Next(); err == nil {
+		fail(state, pos, "unexpected trailing data")
+	}
+	return nil
+})
+
+type decoder struct {
+	opts  Options
+	depth int
+}
+
+func (d *decoder) enter(state goP2.State, pos int) {
+	d.depth++
+	if d.depth > d.opts.MaxDepth {
+		fail(state, pos, "exceeded max depth %d", d.opts.MaxDepth)
+	}
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+func u8(state goP2.State) int             { return int(goP2.Uint8.Exec(state).(uint8)) }
+func u16(state goP2.State) int            { return int(goP2.Uint16BE.Exec(state).(uint16)) }
+func u32(state goP2.State) int            { return int(goP2.Uint32BE.Exec(state).(uint32)) }
+func take(state goP2.State, n int) []byte { return goP2.Take(n).Exec(state).([]byte) }
+
+func (d *decoder) value(state goP2.State) interface{} {
+	pos := state.Pos()
+	b := goP2.Uint8.Exec(state).(uint8)
+	switch {
+	case b <= 0x7f:
+		return int64(b)
+	case b <= 0x8f:
+		return d.mapping(state, pos, int(b&0x0f))
+	case b <= 0x9f:
+		return d.array(state, pos, int(b&0x0f))
+	case b <= 0xbf:
+		return str(state, pos, int(b&0x1f))
+	case b >= 0xe0:
+		return int64(int8(b))
+	}
+	switch b {
+	case 0xc0:
+		return nil
+	case 0xc2:
+		return false
+	case 0xc3:
+		return true
+	case 0xc4:
+		return take(state, u8(state))
+	case 0xc5:
+		return take(state, u16(state))
+	case 0xc6:
+		return take(state, u32(state))
+	case 0xc7:
+		return ext(state, pos, u8(state))
+	case 0xc8:
+		return ext(state, pos, u16(state))
+	case 0xc9:
+		return ext(state, pos, u32(state))
+	case 0xca:
+		return goP2.Float32BE.Exec(state)
+	case 0xcb:
+		return goP2.Float64BE.Exec(state)
+	case 0xcc:
+		return int64(goP2.Uint8.Exec(state).(uint8))
+	case 0xcd:
+		return int64(goP2.Uint16BE.Exec(state).(uint16))
+	case 0xce:
+		return int64(goP2.Uint32BE.Exec(state).(uint32))
+	case 0xcf:
+		v := goP2.Uint64BE.Exec(state).(uint64)
+		if v > math.MaxInt64 {
+			return v
+		}
+		return int64(v)
+	case 0xd0:
+		return int64(goP2.Int8.Exec(state).(int8))
+	case 0xd1:
+		return int64(goP2.Int16BE.Exec(state).(int16))
+	case 0xd2:
+		return int64(goP2.Int32BE.Exec(state).(int32))
+	case 0xd3:
+		return goP2.Int64BE.Exec(state)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return ext(state, pos, 1<<(b-0xd4))
+	case 0xd9:
+		return str(state, pos, u8(state))
+	case 0xda:
+		return str(state, pos, u16(state))
+	case 0xdb:
+		return str(state, pos, u32(state))
+	case 0xdc:
+		return d.array(state, pos, u16(state))
+	case 0xdd:
+		return d.array(state, pos, u32(state))
+	case 0xde:
+		return d.mapping(state, pos, u16(state))
+	case 0xdf:
+		return d.mapping(state, pos, u32(state))
+	}
+	fail(state, pos, "invalid format 0x%02x", b)
+	return nil
+}
+
+func str(state goP2.State, pos, n int) string {
+	b := take(state, n)
+	if !utf8.Valid(b) {
+		fail(state, pos, "str is not valid UTF-8")
+	}
+	return string(b)
+}
+
+// ext 读取扩展类型，时间戳扩展解码为 time.Time
+func ext(state goP2.State, pos, n int) interface{} {
+	typ := goP2.Int8.Exec(state).(int8)
+	data := take(state, n)
+	if typ != TimestampType {
+		return Ext{typ, data}
+	}
+	sub := goP2.BasicStateFromBytes(data)
+	switch n {
+	case 4:
+		return time.Unix(int64(goP2.Uint32BE.Exec(&sub).(uint32)), 0).UTC()
+	case 8:
+		v := goP2.Uint64BE.Exec(&sub).(uint64)
+		nsec, sec := int64(v>>34), int64(v&(1<<34-1))
+		if nsec > 999999999 {
+			fail(state, pos, "timestamp nanoseconds out of range")
+		}
+		return time.Unix(sec, nsec).UTC()
+	case 12:
+		nsec := int64(goP2.Uint32BE.Exec(&sub).(uint32))
+		sec := goP2.Int64BE.Exec(&sub).(int64)
+		if nsec > 999999999 {
+			fail(state, pos, "timestamp nanoseconds out of range")
+		}
+		return time.Unix(sec, nsec).UTC()
+	}
+	fail(state, pos, "invalid timestamp length %d", n)
+	return nil
+}
+
+func (d *decoder) array(state goP2.State, pos, n int) interface{} {
+	d.enter(state, pos)
+	defer d.leave()
+	// 不按照长度字段预先分配，伪造的长度会在读到结尾时失败
+	re := []interface{}{}
+	for i := 0; i < n; i++ {
+		re = append(re, d.value(state))
+	}
+	return re
+}
+
+func (d *decoder) mapping(state goP2.State, pos, n int) interface{} {
+	d.enter(state, pos)
+	defer d.leave()
+	re := map[interface{}]interface{}{}
+	for i := 0; i < n; i++ {
+		keyPos := state.Pos()
+		key := d.value(state)
+		switch key.(type) {
+		case []byte, []interface{}, map[interface{}]interface{}, Ext:
+			fail(state, keyPos, "unhashable map key of type %T", key)
+		}
+		if _, ok := re[key]; ok {
+			fail(state, keyPos, "duplicate map key %v", key)
+		}
+		re[key] = d.value(state)
+	}
+	return re
+}
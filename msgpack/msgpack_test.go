@@ -0,0 +1,118 @@
+package msgpack
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeHex(t *testing.T, text string) (interface{}, error) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Decode(data, Options{})
+}
+
+func TestFormats(t *testing.T) {
+	for _, c := range []struct {
+		hex    string
+		expect interface{}
+	}{
+		{"00", int64(0)},
+		{"7f", int64(127)},
+		{"ff", int64(-1)},
+		{"e0", int64(-32)},
+		{"c0", nil},
+		{"c2", false},
+		{"c3", true},
+		{"cc80", int64(128)},
+		{"cd0100", int64(256)},
+		{"ce00010000", int64(65536)},
+		{"cf7fffffffffffffff", int64(math.MaxInt64)},
+		{"cfffffffffffffffff", uint64(math.MaxUint64)},
+		{"d080", int64(-128)},
+		{"d1ff00", int64(-256)},
+		{"d2ffff0000", int64(-65536)},
+		{"d38000000000000000", int64(math.MinInt64)},
+		{"ca3fc00000", float32(1.5)},
+		{"cb3ff8000000000000", 1.5},
+		{"a3616263", "abc"},
+		{"d903616263", "abc"},
+		{"da0003616263", "abc"},
+		{"db00000003616263", "abc"},
+		{"c403010203", []byte{1, 2, 3}},
+		{"c50001ff", []byte{0xff}},
+		{"c600000000", []byte{}},
+		{"93010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"dc000190", []interface{}{[]interface{}{}}},
+		{"dd00000000", []interface{}{}},
+		{"82a16101a162c0", map[interface{}]interface{}{"a": int64(1), "b": nil}},
+		{"de00010102", map[interface{}]interface{}{int64(1): int64(2)}},
+		{"d40105", Ext{1, []byte{5}}},
+		{"d5020102", Ext{2, []byte{1, 2}}},
+		{"c70305010203", Ext{5, []byte{1, 2, 3}}},
+		{"c8000107ff", Ext{7, []byte{0xff}}},
+		{"d6ff514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"d7ff77359400514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 5e8, time.UTC)},
+		{"c70cff1dcd6500ffffffffffffffff", time.Date(1969, 12, 31, 23, 59, 59, 5e8, time.UTC)},
+	} {
+		re, err := decodeHex(t, c.hex)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", c.hex, err)
+			continue
+		}
+		if tm, ok := c.expect.(time.Time); ok {
+			if got, ok := re.(time.Time); !ok || !got.Equal(tm) {
+				t.Errorf("%s: Expect %v but %v", c.hex, tm, re)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(re, c.expect) {
+			t.Errorf("%s: Expect %#v but %#v", c.hex, c.expect, re)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, c := range []struct {
+		hex     string
+		offset  int
+		message string
+	}{
+		{"c1", 0, "invalid format"},
+		{"a2ff", 2, "eof"},
+		{"a2c328", 0, "UTF-8"},
+		{"92c0", 2, "eof"},
+		{"82c0c0c0c0", 3, "duplicate map key"},
+		{"8191c0c0", 1, "unhashable"},
+		{"c702ff0102", 0, "invalid timestamp length"},
+		{"d7ffffffffffffffffff", 0, "nanoseconds"},
+		{"c0c0", 1, "trailing"},
+		{"c6ffffffff", 5, "eof"},
+	} {
+		_, err := decodeHex(t, c.hex)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: Expect *ParseError but %v", c.hex, err)
+			continue
+		}
+		if e.Offset != c.offset || !strings.Contains(e.Message, c.message) {
+			t.Errorf("%s: Expect %s at %d but %v", c.hex, c.message, c.offset, e)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	data := []byte(strings.Repeat("\x91", 20) + "\xc0")
+	if _, err := Decode(data, Options{MaxDepth: 20}); err != nil {
+		t.Fatalf("Expect success at depth 20 but %v", err)
+	}
+	_, err := Decode(data, Options{MaxDepth: 10})
+	if e, ok := err.(*ParseError); !ok || e.Offset != 10 || !strings.Contains(e.Message, "max depth") {
+		t.Fatalf("Expect max depth error at 10 but %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+// Package netaddr 提供严格的网络地址解析算子：IPv4 、 IPv6 （包括 :: 压缩、内嵌 IPv4 和 zone）、
+// CIDR 前缀以及 host:port ，结果为 net/netip 中的类型。
+//
+// 这些算子只消费地址本身，之后的输入留给外层的文法处理。
+package netaddr
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+const (
+	decimal = "0123456789"
+	hex     = "0123456789abcdefABCDEF"
+)
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// digits 解析由 set 中的字符组成的非空序列
+func digits(set string) goP2.P {
+	return goP2.Many1(goP2.RuneOf(set)).Bind(goP2.ReturnString)
+}
+
+// decimalNumber 解析不超过 max 的十进制数， leadingZeros 为 false 时拒绝前导零
+func decimalNumber(state goP2.State, name string, max int, leadingZeros bool) int {
+	pos := state.Pos()
+	text := digits(decimal).Exec(state).(string)
+	if !leadingZeros && len(text) > 1 && text[0] == '0' {
+		fail(state, pos, "leading zeros in %s %s", name, text)
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v > max {
+		fail(state, pos, "%s %s out of range", name, text)
+	}
+	return v
+}
+
+func ipv4(leadingZeros bool) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		var octets [4]byte
+		for i := range octets {
+			if i > 0 {
+				goP2.Chr('.').Exec(state)
+			}
+			octets[i] = byte(decimalNumber(state, "octet", 255, leadingZeros))
+		}
+		return netip.AddrFrom4(octets)
+	})
+}
+
+// IPv4 解析点分十进制的 IPv4 地址，每个八位组在 0 到 255 之间，不允许前导零，结果为 netip.Addr
+var IPv4 = ipv4(false)
+
+// IPv4LeadingZeros 与 IPv4 相同，但是允许八位组带有前导零，前导零不表示八进制
+var IPv4LeadingZeros = ipv4(true)
+
+var h16 = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	text := digits(hex).Exec(state).(string)
+	if len(text) > 4 {
+		fail(state, pos, "IPv6 group %s is too long", text)
+	}
+	v, _ := strconv.ParseUint(text, 16, 16)
+	return uint16(v)
+})
+
+var zone = goP2.Chr('%').Then(goP2.Many1(goP2.RuneNone("]/% \t\r\n"))).Bind(goP2.ReturnString)
+
+// ipv6Groups 按照 RFC 4291 解析 IPv6 地址，返回 16 个字节
+func ipv6Groups(state goP2.State) [16]byte {
+	start := state.Pos()
+	var head, tail []uint16
+	double := false
+	if _, err := goP2.Try(goP2.Str("::")).Parse(state); err == nil {
+		double = true
+	}
+	groups := func() int { return len(head) + len(tail) }
+	push := func(g uint16) {
+		if double {
+			tail = append(tail, g)
+		} else {
+			head = append(head, g)
+		}
+	}
+	for groups() < 8 {
+		// 最后 32 位可以写成 IPv4 形式
+		if groups() <= 6 {
+			if v4, err := goP2.Try(IPv4).Parse(state); err == nil {
+				b := v4.(netip.Addr).As4()
+				push(uint16(b[0])<<8 | uint16(b[1]))
+				push(uint16(b[2])<<8 | uint16(b[3]))
+				break
+			}
+		}
+		g, err := goP2.Try(h16).Parse(state)
+		if err != nil {
+			// 只有 :: 之后可以没有分组
+			if double && len(tail) == 0 {
+				break
+			}
+			fail(state, start, "Expect an IPv6 address")
+		}
+		push(g.(uint16))
+		if _, err := goP2.Try(goP2.Str("::")).Parse(state); err == nil {
+			if double {
+				fail(state, start, "IPv6 address contains more than one '::'")
+			}
+			double = true
+			continue
+		}
+		if _, err := goP2.Try(goP2.Chr(':').Then(goP2.Ahead(goP2.RuneOf(hex)))).Parse(state); err != nil {
+			break
+		}
+	}
+	if double && groups() > 7 || !double && groups() != 8 {
+		fail(state, start, "IPv6 address must have 8 groups or use '::'")
+	}
+	var re [16]byte
+	all := append(append(head, make([]uint16, 8-groups())...), tail...)
+	for i, g := range all {
+		re[2*i] = byte(g >> 8)
+		re[2*i+1] = byte(g)
+	}
+	return re
+}
+
+// IPv6 解析 IPv6 地址，支持 :: 压缩、内嵌的 IPv4 形式以及 %zone 后缀，结果为 netip.Addr
+var IPv6 = goP2.Do(func(state goP2.State) interface{} {
+	addr := netip.AddrFrom16(ipv6Groups(state))
+	if z, err := goP2.Try(zone).Parse(state); err == nil {
+		addr = addr.WithZone(z.(string))
+	}
+	return addr
+})
+
+// ipv6NoZone 是不带 zone 的 IPv6 地址，用于 CIDR 前缀
+var ipv6NoZone = goP2.Do(func(state goP2.State) interface{} {
+	return netip.AddrFrom16(ipv6Groups(state))
+})
+
+// isIPv6 向前查看地址的字符，含有冒号的是 IPv6 地址
+func isIPv6(state goP2.State) bool {
+	text, _ := goP2.Ahead(goP2.Many(goP2.RuneOf(hex + ".:"))).Parse(state)
+	return strings.Contains(goP2.ToString(text), ":")
+}
+
+// IP 解析 IPv4 或者 IPv6 地址，结果为 netip.Addr
+var IP = goP2.Do(func(state goP2.State) interface{} {
+	if isIPv6(state) {
+		return IPv6.Exec(state)
+	}
+	return IPv4.Exec(state)
+})
+
+// Prefix 解析 addr/bits 形式的 CIDR 前缀，结果为 netip.Prefix ，地址中超出前缀的位会被保留
+var Prefix = goP2.Do(func(state goP2.State) interface{} {
+	var addr netip.Addr
+	if isIPv6(state) {
+		addr = ipv6NoZone.Exec(state).(netip.Addr)
+	} else {
+		addr = IPv4.Exec(state).(netip.Addr)
+	}
+	goP2.Chr('/').Exec(state)
+	bits := decimalNumber(state, "prefix length", addr.BitLen(), false)
+	return netip.PrefixFrom(addr, bits)
+})
+
+// Port 解析 0 到 65535 之间的端口号，结果为 uint16
+var Port = goP2.Do(func(state goP2.State) interface{} {
+	return uint16(decimalNumber(state, "port", 65535, true))
+})
+
+// bracketed 解析 [IPv6] 形式的地址
+var bracketed = goP2.Between(goP2.Chr('['), goP2.Chr(']'), IPv6)
+
+// AddrPort 解析 IPv4:port 或者 [IPv6]:port ，结果为 netip.AddrPort
+var AddrPort = goP2.Do(func(state goP2.State) interface{} {
+	var addr netip.Addr
+	if _, err := goP2.Ahead(goP2.Chr('[')).Parse(state); err == nil {
+		addr = bracketed.Exec(state).(netip.Addr)
+	} else {
+		addr = IPv4.Exec(state).(netip.Addr)
+	}
+	goP2.Chr(':').Exec(state)
+	return netip.AddrPortFrom(addr, Port.Exec(state).(uint16))
+})
+
+// Endpoint 是 host:port 的解析结果。 host 为 IP 地址时 Addr 有效，否则 Host 是主机名
+type Endpoint struct {
+	Host string
+	Addr netip.Addr
+	Port uint16
+}
+
+func (e Endpoint) String() string {
+	if e.Addr.Is6() {
+		return "[" + e.Host + "]:" + strconv.Itoa(int(e.Port))
+	}
+	return e.Host + ":" + strconv.Itoa(int(e.Port))
+}
+
+func isLabelRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_'
+}
+
+// Hostname 解析点分的主机名，每个标签由字母、数字、连字符和下划线组成，长度为 1 到 63 ，
+// 不能以连字符开头或结尾。全部由数字组成的最后一个标签会被当作错误的 IPv4 地址拒绝。结果为 string
+var Hostname = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	label := goP2.Many1(goP2.RuneP("hostname", isLabelRune)).Bind(goP2.ReturnString)
+	labels := []string{label.Exec(state).(string)}
+	for {
+		l, err := goP2.Try(goP2.Chr('.').Then(label)).Parse(state)
+		if err != nil {
+			break
+		}
+		labels = append(labels, l.(string))
+	}
+	for _, l := range labels {
+		if len(l) > 63 || strings.HasPrefix(l, "-") || strings.HasSuffix(l, "-") {
+			fail(state, start, "invalid hostname label %s", l)
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], decimal) == "" {
+		fail(state, start, "invalid IPv4 address %s", strings.Join(labels, "."))
+	}
+	return strings.Join(labels, ".")
+})
+
+// HostPort 解析 host:port ， host 可以是主机名、 IPv4 地址或者 [IPv6] ，结果为 Endpoint
+var HostPort = goP2.Do(func(state goP2.State) interface{} {
+	var re Endpoint
+	if _, err := goP2.Ahead(goP2.Chr('[')).Parse(state); err == nil {
+		re.Addr = bracketed.Exec(state).(netip.Addr)
+		re.Host = re.Addr.String()
+	} else if addr, err := goP2.Try(IPv4.Over(goP2.Ahead(goP2.Chr(':')))).Parse(state); err == nil {
+		// 只有后面紧跟端口时才是 IPv4 地址，否则 10.0.0.1.nip.io 之类的主机名按照 Hostname 解析
+		re.Addr = addr.(netip.Addr)
+		re.Host = re.Addr.String()
+	} else {
+		re.Host = Hostname.Exec(state).(string)
+	}
+	goP2.Chr(':').Exec(state)
+	re.Port = Port.Exec(state).(uint16)
+	return re
+})
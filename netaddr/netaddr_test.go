@@ -0,0 +1,121 @@
+package netaddr
+
+import (
+	"net/netip"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func parse(p goP2.P, text string) (interface{}, error) {
+	state := goP2.BasicStateFromText(text)
+	return p.Over(goP2.EOF).Parse(&state)
+}
+
+func TestIP(t *testing.T) {
+	for _, text := range []string{
+		"0.0.0.0", "127.0.0.1", "255.255.255.255",
+		"::", "::1", "1::", "2001:db8::8a2e:370:7334", "2001:0db8:0000:0000:0000:ff00:0042:8329",
+		"::ffff:192.0.2.128", "64:ff9b::192.0.2.33", "1:2:3:4:5:6:7::", "::2:3:4:5:6:7:8",
+		"1:2:3:4:5:6:1.2.3.4", "fe80::1%eth0",
+	} {
+		re, err := parse(IP, text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", text, err)
+			continue
+		}
+		expect := netip.MustParseAddr(text)
+		if re.(netip.Addr) != expect {
+			t.Errorf("%s: Expect %v but %v", text, expect, re)
+		}
+	}
+}
+
+func TestIPInvalid(t *testing.T) {
+	for _, text := range []string{
+		"999.999.999.999", "256.1.1.1", "1.2.3", "01.2.3.4", "1.2.3.4.5",
+		"1:2:3:4:5:6:7:8:9", "1::2::3", "12345::", "1:2:3:4:5:6:7", ":1:2", "1:2:3:4:5:6:7:1.2.3.4",
+		"fe80::1%", "::1.2.3",
+	} {
+		if re, err := parse(IP, text); err == nil {
+			t.Errorf("%s: Expect error but %v", text, re)
+		}
+	}
+}
+
+func TestIPv4LeadingZeros(t *testing.T) {
+	re, err := parse(IPv4LeadingZeros, "010.001.000.009")
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if re.(netip.Addr) != netip.MustParseAddr("10.1.0.9") {
+		t.Fatalf("Expect 10.1.0.9 but %v", re)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	for _, text := range []string{"10.0.0.0/8", "192.168.1.7/24", "0.0.0.0/0", "2001:db8::/32", "::1/128"} {
+		re, err := parse(Prefix, text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", text, err)
+			continue
+		}
+		if re.(netip.Prefix) != netip.MustParsePrefix(text) {
+			t.Errorf("%s: Expect %v but %v", text, netip.MustParsePrefix(text), re)
+		}
+	}
+	for _, text := range []string{"10.0.0.0/33", "::/129", "10.0.0.0/08", "fe80::1%eth0/64", "10.0.0.0"} {
+		if re, err := parse(Prefix, text); err == nil {
+			t.Errorf("%s: Expect error but %v", text, re)
+		}
+	}
+}
+
+func TestAddrPort(t *testing.T) {
+	for _, text := range []string{"127.0.0.1:8080", "[::1]:443", "[fe80::1%eth0]:22"} {
+		re, err := parse(AddrPort, text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", text, err)
+			continue
+		}
+		if re.(netip.AddrPort) != netip.MustParseAddrPort(text) {
+			t.Errorf("%s: Expect %v but %v", text, netip.MustParseAddrPort(text), re)
+		}
+	}
+	for _, text := range []string{"::1:443", "1.2.3.4:65536", "1.2.3.4"} {
+		if re, err := parse(AddrPort, text); err == nil {
+			t.Errorf("%s: Expect error but %v", text, re)
+		}
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		host string
+		addr bool
+		port uint16
+	}{
+		{"example.com:80", "example.com", false, 80},
+		{"localhost:0", "localhost", false, 0},
+		{"10.0.0.1:53", "10.0.0.1", true, 53},
+		{"[2001:db8::1]:8443", "2001:db8::1", true, 8443},
+		{"10.0.0.1.nip.io:80", "10.0.0.1.nip.io", false, 80},
+		{"1.2.3.4x:80", "1.2.3.4x", false, 80},
+	} {
+		re, err := parse(HostPort, c.text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", c.text, err)
+			continue
+		}
+		e := re.(Endpoint)
+		if e.Host != c.host || e.Addr.IsValid() != c.addr || e.Port != c.port || e.String() != c.text {
+			t.Errorf("%s: Expect %s %v %d but %#v", c.text, c.host, c.addr, c.port, e)
+		}
+	}
+	for _, text := range []string{"-bad.com:80", "1.2.3.999:80", "host:", "host"} {
+		if re, err := parse(HostPort, text); err == nil {
+			t.Errorf("%s: Expect error but %v", text, re)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package protowire 用 goP2 的字节算子解码 protobuf 的线格式，不需要 .proto 文件。
+//
+// 没有 schema 时，字段按照线类型解码为通用的字段树：varint 和 fixed64 为 uint64 ， fixed32 为 uint32 ，
+// length-delimited 为 []byte ，group 为 []Field 。提供 Schema 时按照字段的类型解码为对应的 Go 值，
+// 嵌套消息递归解码，标量的 length-delimited 记录按照 packed 编码解码。
+package protowire
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// WireType 是 protobuf 的线类型
+type WireType int
+
+// protobuf 定义的线类型
+const (
+	VarintType     WireType = 0
+	Fixed64Type    WireType = 1
+	BytesType      WireType = 2
+	StartGroupType WireType = 3
+	EndGroupType   WireType = 4
+	Fixed32Type    WireType = 5
+)
+
+var wireTypeNames = map[WireType]string{
+	VarintType:     "varint",
+	Fixed64Type:    "fixed64",
+	BytesType:      "bytes",
+	StartGroupType: "start group",
+	EndGroupType:   "end group",
+	Fixed32Type:    "fixed32",
+}
+
+func (t WireType) String() string {
+	if name, ok := wireTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("wire type %d", int(t))
+}
+
+// MaxNumber 是字段编号的最大值
+const MaxNumber = 1<<29 - 1
+
+// Tag 是字段的标签，由字段编号和线类型组成
+type Tag struct {
+	Number int
+	Type   WireType
+}
+
+// Kind 是 Schema 中字段的类型
+type Kind int
+
+// Schema 支持的字段类型
+const (
+	Int32 Kind = iota + 1
+	Int64
+	Uint32
+	Uint64
+	Sint32
+	Sint64
+	Bool
+	Enum
+	Fixed32
+	Fixed64
+	Sfixed32
+	Sfixed64
+	Float
+	Double
+	String
+	Bytes
+	Message
+	Group
+)
+
+// wireType 返回类型对应的线类型
+func (k Kind) wireType() WireType {
+	switch k {
+	case Fixed32, Sfixed32, Float:
+		return Fixed32Type
+	case Fixed64, Sfixed64, Double:
+		return Fixed64Type
+	case String, Bytes, Message:
+		return BytesType
+	case Group:
+		return StartGroupType
+	}
+	return VarintType
+}
+
+// scalar 判断类型是否是可以 packed 编码的标量
+func (k Kind) scalar() bool {
+	return k.wireType() == VarintType || k.wireType() == Fixed32Type || k.wireType() == Fixed64Type
+}
+
+// FieldSchema 描述一个字段， Message 是 Message 和 Group 类型字段的子 schema
+type FieldSchema struct {
+	Name    string
+	Kind    Kind
+	Message Schema
+}
+
+// Schema 是字段编号到字段描述的映射，没有出现在 Schema 中的字段按照通用方式解码
+type Schema map[int]FieldSchema
+
+// Field 是解码出的一个字段， Offset 是标签在输入中的字节偏移
+type Field struct {
+	Number int
+	Type   WireType
+	// Name 是 Schema 中的字段名，没有 Schema 时为空
+	Name string
+	// Value 在没有 Schema 时是 uint64 、 uint32 、 []byte 或者 []Field ，有 Schema 时是对应的 Go 类型，
+	// packed 字段为 []interface{}
+	Value  interface{}
+	Offset int
+}
+
+// DefaultMaxDepth 是 Options.MaxDepth 为 0 时使用的嵌套深度上限
+const DefaultMaxDepth = 1000
+
+// Options 控制解码的行为
+type Options struct {
+	// MaxDepth 是 group 和嵌套消息深度的上限，为 0 时使用 DefaultMaxDepth
+	MaxDepth int
+}
+
+// ParseError 是解码错误， Offset 是字节偏移
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("protowire: offset %d: %s", e.Offset, e.Message)
+}
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// atEnd 判断是否已经没有后续的数据
+func atEnd(state goP2.State) bool {
+	pos := state.Pos()
+	if _, err := state.Next(); err != nil {
+		return true
+	}
+	state.SeekTo(pos)
+	return false
+}
+
+// TagP 解析字段标签，结果为 Tag
+var TagP = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	v := goP2.P(goP2.Uvarint).Exec(state).(uint64)
+	number, wire := v>>3, WireType(v&7)
+	if number < 1 || number > MaxNumber {
+		fail(state, pos, "invalid field number %d", number)
+	}
+	if wire > Fixed32Type {
+		fail(state, pos, "invalid wire type %d", int(wire))
+	}
+	return Tag{int(number), wire}
+})
+
+// Fields 返回解析消息全部字段的算子，结果为 []Field ， schema 可以为 nil
+func Fields(schema Schema) goP2.P {
+	return FieldsWith(schema, Options{})
+}
+
+// FieldsWith 返回按照 opts 解析消息全部字段的算子，参见 Fields
+func FieldsWith(schema Schema, opts Options) goP2.P {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	return goP2.Do(func(state goP2.State) interface{} {
+		d := &decoder{opts: opts}
+		return d.fields(state, schema, 0)
+	})
+}
+
+type decoder struct {
+	opts  Options
+	depth int
+}
+
+func (d *decoder) enter(state goP2.State, pos int) {
+	d.depth++
+	if d.depth > d.opts.MaxDepth {
+		fail(state, pos, "exceeded max depth %d", d.opts.MaxDepth)
+	}
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// fields 解析字段直到输入结束，或者在 group 不为 0 时直到编号相同的 end group
+func (d *decoder) fields(state goP2.State, schema Schema, group int) []Field {
+	d.enter(state, state.Pos())
+	defer d.leave()
+	re := []Field{}
+	for {
+		if atEnd(state) {
+			if group != 0 {
+				panic(state.Trap("unterminated group %d", group))
+			}
+			return re
+		}
+		pos := state.Pos()
+		tag := TagP.Exec(state).(Tag)
+		if tag.Type == EndGroupType {
+			if tag.Number != group {
+				fail(state, pos, "unexpected end group %d", tag.Number)
+			}
+			return re
+		}
+		f := Field{Number: tag.Number, Type: tag.Type, Offset: pos}
+		if fs, ok := schema[tag.Number]; ok {
+			f.Name = fs.Name
+			f.Value = d.typed(state, pos, tag, fs)
+		} else {
+			f.Value = d.raw(state, tag)
+		}
+		re = append(re, f)
+	}
+}
+
+var length = goP2.Do(func(state goP2.State) interface{} {
+	pos := state.Pos()
+	n := goP2.P(goP2.Uvarint).Exec(state).(uint64)
+	if n > math.MaxInt32 {
+		fail(state, pos, "length %d is too large", n)
+	}
+	return int(n)
+})
+
+// raw 按照线类型解码字段的值
+func (d *decoder) raw(state goP2.State, tag Tag) interface{} {
+	switch tag.Type {
+	case VarintType:
+		return goP2.P(goP2.Uvarint).Exec(state)
+	case Fixed64Type:
+		return goP2.Uint64LE.Exec(state)
+	case Fixed32Type:
+		return goP2.Uint32LE.Exec(state)
+	case BytesType:
+		return goP2.Take(length.Exec(state).(int)).Exec(state)
+	}
+	return d.fields(state, nil, tag.Number)
+}
+
+// scalar 读取一个 kind 类型的标量
+func scalar(state goP2.State, kind Kind) interface{} {
+	switch kind.wireType() {
+	case Fixed32Type:
+		v := goP2.Uint32LE.Exec(state).(uint32)
+		switch kind {
+		case Sfixed32:
+			return int32(v)
+		case Float:
+			return math.Float32frombits(v)
+		}
+		return v
+	case Fixed64Type:
+		v := goP2.Uint64LE.Exec(state).(uint64)
+		switch kind {
+		case Sfixed64:
+			return int64(v)
+		case Double:
+			return math.Float64frombits(v)
+		}
+		return v
+	}
+	v := goP2.P(goP2.Uvarint).Exec(state).(uint64)
+	switch kind {
+	case Int32, Enum:
+		return int32(v)
+	case Int64:
+		return int64(v)
+	case Uint32:
+		return uint32(v)
+	case Sint32:
+		return int32(uint32(v)>>1) ^ -int32(v&1)
+	case Sint64:
+		return int64(v>>1) ^ -int64(v&1)
+	case Bool:
+		return v != 0
+	}
+	return v
+}
+
+// typed 按照 schema 解码字段的值
+func (d *decoder) typed(state goP2.State, pos int, tag Tag, fs FieldSchema) interface{} {
+	// 标量可以使用 packed 编码
+	if fs.Kind.scalar() && tag.Type == BytesType {
+		n := length.Exec(state).(int)
+		return goP2.Sized(n, goP2.Do(func(state goP2.State) interface{} {
+			re := []interface{}{}
+			for !atEnd(state) {
+				re = append(re, scalar(state, fs.Kind))
+			}
+			return re
+		})).Exec(state)
+	}
+	if tag.Type != fs.Kind.wireType() {
+		fail(state, pos, "field %d (%s) expects %s but got %s", tag.Number, fs.Name, fs.Kind.wireType(), tag.Type)
+	}
+	switch fs.Kind {
+	case String:
+		start := state.Pos()
+		b := goP2.Take(length.Exec(state).(int)).Exec(state).([]byte)
+		if !utf8.Valid(b) {
+			fail(state, start, "field %d (%s) is not valid UTF-8", tag.Number, fs.Name)
+		}
+		return string(b)
+	case Bytes:
+		return goP2.Take(length.Exec(state).(int)).Exec(state)
+	case Message:
+		return goP2.Sized(length.Exec(state).(int), goP2.Do(func(state goP2.State) interface{} {
+			return d.fields(state, fs.Message, 0)
+		})).Exec(state)
+	case Group:
+		return d.fields(state, fs.Message, tag.Number)
+	}
+	return scalar(state, fs.Kind)
+}
+
+// Parse 解码 data 中的消息， schema 可以为 nil ，错误总是 *ParseError
+func Parse(data []byte, schema Schema) ([]Field, error) {
+	return ParseWith(data, schema, Options{})
+}
+
+// ParseWith 按照 opts 解码 data 中的消息，参见 Parse
+func ParseWith(data []byte, schema Schema, opts Options) ([]Field, error) {
+	state := goP2.BasicStateFromBytes(data)
+	re, err := FieldsWith(schema, opts).Parse(&state)
+	if err != nil {
+		if e, ok := err.(goP2.Error); ok {
+			return nil, &ParseError{e.Pos, e.Message}
+		}
+		return nil, &ParseError{state.Pos(), err.Error()}
+	}
+	return re.([]Field), nil
+}
@@ -0,0 +1,133 @@
+package protowire
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sample 的字段依次为：
+// 1: varint 150 ; 2: string "testing" ; 3: 嵌套消息 {1: 150} ; 4: packed [3, 270, 86942] ;
+// 5: group {1: 1} ; 6: fixed32 ; 7: fixed64 ; 8: sint32 -2
+var sample = []byte{
+	0x08, 0x96, 0x01,
+	0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g',
+	0x1a, 0x03, 0x08, 0x96, 0x01,
+	0x22, 0x06, 0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05,
+	0x2b, 0x08, 0x01, 0x2c,
+	0x35, 0x00, 0x00, 0x80, 0x3f,
+	0x39, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0x40, 0x03,
+}
+
+func TestRaw(t *testing.T) {
+	fields, err := Parse(sample, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Field{
+		{1, VarintType, "", uint64(150), 0},
+		{2, BytesType, "", []byte("testing"), 3},
+		{3, BytesType, "", []byte{0x08, 0x96, 0x01}, 12},
+		{4, BytesType, "", []byte{0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05}, 17},
+		{5, StartGroupType, "", []Field{{1, VarintType, "", uint64(1), 26}}, 25},
+		{6, Fixed32Type, "", uint32(0x3f800000), 29},
+		{7, Fixed64Type, "", uint64(0xfffffffffffffffe), 34},
+		{8, VarintType, "", uint64(3), 43},
+	}
+	if !reflect.DeepEqual(fields, expect) {
+		t.Fatalf("Expect %v but %v", expect, fields)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	inner := Schema{1: {"a", Int32, nil}}
+	schema := Schema{
+		1: {"a", Int32, nil},
+		2: {"b", String, nil},
+		3: {"c", Message, inner},
+		4: {"d", Uint32, nil},
+		5: {"e", Group, Schema{1: {"flag", Bool, nil}}},
+		6: {"f", Float, nil},
+		7: {"g", Sfixed64, nil},
+		8: {"h", Sint32, nil},
+	}
+	fields, err := Parse(sample, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{}
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+	expect := map[string]interface{}{
+		"a": int32(150),
+		"b": "testing",
+		"c": []Field{{1, VarintType, "a", int32(150), 14}},
+		"d": []interface{}{uint32(3), uint32(270), uint32(86942)},
+		"e": []Field{{1, VarintType, "flag", true, 26}},
+		"f": float32(1),
+		"g": int64(-2),
+		"h": int32(-2),
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Fatalf("Expect %v but %v", expect, values)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, c := range []struct {
+		data    []byte
+		schema  Schema
+		offset  int
+		message string
+	}{
+		{[]byte{0x00}, nil, 0, "invalid field number"},
+		{[]byte{0x0e}, nil, 0, "invalid wire type"},
+		{[]byte{0x12, 0x05, 'a'}, nil, 3, "eof"},
+		{[]byte{0x2b, 0x08, 0x01}, nil, 3, "unterminated group"},
+		{[]byte{0x2c}, nil, 0, "unexpected end group"},
+		{[]byte{0x0d, 0, 0, 0, 0}, Schema{1: {"a", Int64, nil}}, 0, "expects varint"},
+		{[]byte{0x0a, 0x01, 0xff}, Schema{1: {"s", String, nil}}, 1, "UTF-8"},
+		{[]byte{0x0a, 0x02, 0x08}, Schema{1: {"m", Message, nil}}, 3, "eof"},
+	} {
+		_, err := Parse(c.data, c.schema)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%x: Expect *ParseError but %v", c.data, err)
+			continue
+		}
+		if e.Offset != c.offset || !strings.Contains(e.Message, c.message) {
+			t.Errorf("%x: Expect %s at %d but %v", c.data, c.message, c.offset, e)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	// 顶层消息算作一层， 19 层 group 一共 20 层
+	data := append(bytes.Repeat([]byte{0x0b}, 19), bytes.Repeat([]byte{0x0c}, 19)...)
+	if _, err := ParseWith(data, nil, Options{MaxDepth: 20}); err != nil {
+		t.Fatalf("Expect 20 levels pass but %v", err)
+	}
+	if _, err := ParseWith(data, nil, Options{MaxDepth: 19}); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("Expect max depth error but %v", err)
+	}
+
+	// 嵌套消息同样计入深度
+	schema := Schema{}
+	schema[1] = FieldSchema{"m", Message, schema}
+	msg := []byte{}
+	for i := 0; i < 5; i++ {
+		msg = append([]byte{0x0a, byte(len(msg))}, msg...)
+	}
+	if _, err := ParseWith(msg, schema, Options{MaxDepth: 5}); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("Expect max depth error but %v", err)
+	}
+
+	bomb := bytes.Repeat([]byte{0x0b}, 3000000)
+	_, err := Parse(bomb, nil)
+	if e, ok := err.(*ParseError); !ok || !strings.Contains(e.Message, "max depth") {
+		t.Fatalf("Expect max depth *ParseError but %v", err)
+	}
+}
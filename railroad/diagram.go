@@ -0,0 +1,237 @@
+package railroad
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// 布局常量，文字使用等宽字体，宽度按字符数估算
+const (
+	arc       = 10.0
+	gap       = 10.0
+	boxHeight = 22.0
+	charWidth = 8.0
+	padding   = 20.0
+)
+
+// diagram 是铁路图中的一个部件，所有部件都从左侧基线进入，从右侧基线离开。
+// up 和 down 分别是基线以上和以下占用的高度。
+type diagram interface {
+	size() (width, up, down float64)
+	draw(b *strings.Builder, x, y float64)
+}
+
+// box 是终结符、非终结符或者特殊结点的方框
+type box struct {
+	class string
+	text  string
+	href  string
+}
+
+func (d box) size() (float64, float64, float64) {
+	return float64(utf8.RuneCountInString(d.text))*charWidth + 2*arc, boxHeight / 2, boxHeight / 2
+}
+
+func (d box) draw(b *strings.Builder, x, y float64) {
+	w, _, _ := d.size()
+	rx := 0.0
+	if d.class == "terminal" {
+		rx = arc
+	}
+	fmt.Fprintf(b, `<g class="%s">`, d.class)
+	if d.href != "" {
+		fmt.Fprintf(b, `<a href="%s">`, html.EscapeString(d.href))
+	}
+	fmt.Fprintf(b, `<rect x="%g" y="%g" width="%g" height="%g" rx="%g"/>`, x, y-boxHeight/2, w, boxHeight, rx)
+	fmt.Fprintf(b, `<text x="%g" y="%g">%s</text>`, x+w/2, y+4, html.EscapeString(d.text))
+	if d.href != "" {
+		b.WriteString(`</a>`)
+	}
+	b.WriteString(`</g>`)
+}
+
+// skip 是不消费输入的空路径
+type skip struct{}
+
+func (skip) size() (float64, float64, float64) {
+	return 0, 0, 0
+}
+
+func (skip) draw(b *strings.Builder, x, y float64) {}
+
+type sequence []diagram
+
+func (d sequence) size() (float64, float64, float64) {
+	var width, up, down float64
+	for i, item := range d {
+		w, u, dn := item.size()
+		if i > 0 {
+			width += gap
+		}
+		width += w
+		up = math.Max(up, u)
+		down = math.Max(down, dn)
+	}
+	return width, up, down
+}
+
+func (d sequence) draw(b *strings.Builder, x, y float64) {
+	for i, item := range d {
+		if i > 0 {
+			line(b, x, y, x+gap)
+			x += gap
+		}
+		item.draw(b, x, y)
+		w, _, _ := item.size()
+		x += w
+	}
+}
+
+// choice 的第一个分支在基线上，其余分支依次排在下方
+type choice []diagram
+
+// offsets 返回每个分支的基线相对于入口基线的偏移
+func (d choice) offsets() []float64 {
+	re := make([]float64, len(d))
+	var y, prevDown float64
+	for i, item := range d {
+		_, up, down := item.size()
+		if i > 0 {
+			y += math.Max(prevDown+gap+up, 2*arc)
+		}
+		re[i] = y
+		prevDown = down
+	}
+	return re
+}
+
+func (d choice) size() (float64, float64, float64) {
+	var inner float64
+	for _, item := range d {
+		w, _, _ := item.size()
+		inner = math.Max(inner, w)
+	}
+	offsets := d.offsets()
+	_, up, down := d[0].size()
+	last := len(d) - 1
+	if last > 0 {
+		_, _, lastDown := d[last].size()
+		down = offsets[last] + lastDown
+	}
+	return inner + 4*arc, up, down
+}
+
+func (d choice) draw(b *strings.Builder, x, y float64) {
+	width, _, _ := d.size()
+	right := x + width
+	for i, item := range d {
+		w, _, _ := item.size()
+		yi := y + d.offsets()[i]
+		if i == 0 {
+			line(b, x, y, x+2*arc)
+		} else {
+			fmt.Fprintf(b, `<path d="M%g %g a%g %g 0 0 1 %g %g V%g a%g %g 0 0 0 %g %g"/>`,
+				x, y, arc, arc, arc, arc, yi-arc, arc, arc, arc, arc)
+			fmt.Fprintf(b, `<path d="M%g %g a%g %g 0 0 0 %g %g V%g a%g %g 0 0 1 %g %g"/>`,
+				right-2*arc, yi, arc, arc, arc, -arc, y+arc, arc, arc, arc, -arc)
+		}
+		item.draw(b, x+2*arc, yi)
+		line(b, x+2*arc+w, yi, right-2*arc)
+		if i == 0 {
+			line(b, right-2*arc, y, right)
+		}
+	}
+}
+
+// loop 匹配 item 一到多次，回路上可以带有分隔符
+type loop struct {
+	item diagram
+	sep  diagram
+}
+
+func (d loop) inner() float64 {
+	wi, _, _ := d.item.size()
+	ws, _, _ := d.sep.size()
+	return math.Max(wi, ws)
+}
+
+func (d loop) back() float64 {
+	_, _, down := d.item.size()
+	_, up, _ := d.sep.size()
+	return math.Max(down+gap+up, 2*arc)
+}
+
+func (d loop) size() (float64, float64, float64) {
+	_, up, _ := d.item.size()
+	_, _, down := d.sep.size()
+	return d.inner() + 2*arc, up, d.back() + down
+}
+
+func (d loop) draw(b *strings.Builder, x, y float64) {
+	inner := d.inner()
+	wi, _, _ := d.item.size()
+	ws, _, _ := d.sep.size()
+	yb := y + d.back()
+	line(b, x, y, x+arc)
+	d.item.draw(b, x+arc, y)
+	line(b, x+arc+wi, y, x+2*arc+inner)
+	fmt.Fprintf(b, `<path d="M%g %g a%g %g 0 0 1 %g %g V%g a%g %g 0 0 1 %g %g"/>`,
+		x+arc+inner, y, arc, arc, arc, arc, yb-arc, arc, arc, -arc, arc)
+	sx := x + arc + (inner-ws)/2
+	line(b, sx+ws, yb, x+arc+inner)
+	d.sep.draw(b, sx, yb)
+	line(b, x+arc, yb, sx)
+	fmt.Fprintf(b, `<path d="M%g %g a%g %g 0 0 1 %g %g V%g a%g %g 0 0 1 %g %g"/>`,
+		x+arc, yb, arc, arc, -arc, -arc, y+arc, arc, arc, arc, -arc)
+}
+
+// group 用虚线框包围 item 并在上方标注 label ，用于向前查看
+type group struct {
+	label string
+	item  diagram
+}
+
+func (d group) size() (float64, float64, float64) {
+	w, up, down := d.item.size()
+	lw := float64(utf8.RuneCountInString(d.label)) * charWidth
+	return math.Max(w, lw) + 2*gap, up + gap + boxHeight/2, down + gap
+}
+
+func (d group) draw(b *strings.Builder, x, y float64) {
+	width, up, down := d.size()
+	w, _, _ := d.item.size()
+	fmt.Fprintf(b, `<g class="group"><rect x="%g" y="%g" width="%g" height="%g" rx="%g"/>`,
+		x, y-up+boxHeight/2, width, up+down-boxHeight/2, arc)
+	fmt.Fprintf(b, `<text x="%g" y="%g">%s</text></g>`, x+gap, y-up+boxHeight/2-4, html.EscapeString(d.label))
+	line(b, x, y, x+(width-w)/2)
+	d.item.draw(b, x+(width-w)/2, y)
+	line(b, x+(width+w)/2, y, x+width)
+}
+
+func line(b *strings.Builder, x1, y, x2 float64) {
+	if x2 > x1 {
+		fmt.Fprintf(b, `<path d="M%g %g H%g"/>`, x1, y, x2)
+	}
+}
+
+// render 将部件绘制成完整的 svg 元素，两端带有起止标记
+func render(d diagram) string {
+	w, up, down := d.size()
+	width := w + 2*padding + 2*gap
+	height := up + down + 2*padding
+	y := padding + up
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="railroad" xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`,
+		width, height, width, height)
+	fmt.Fprintf(&b, `<path d="M%g %g v%g M%g %g v%g"/>`, padding, y-arc, 2*arc, padding+4, y-arc, 2*arc)
+	line(&b, padding, y, padding+gap)
+	d.draw(&b, padding+gap, y)
+	end := padding + gap + w
+	line(&b, end, y, end+gap)
+	fmt.Fprintf(&b, `<path d="M%g %g v%g M%g %g v%g"/>`, end+gap, y-arc, 2*arc, end+gap-4, y-arc, 2*arc)
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,112 @@
+// Package railroad 从 grammar.Grammar 中登记的规则生成铁路图（SVG/HTML）和 EBNF 文本，
+// 使文档直接来源于解析器的定义，而不是手工绘制。
+package railroad
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+// SVG 将一个结点绘制成 svg 元素。Ref 结点会链接到 #rule-name 锚点。
+func SVG(node *grammar.Node) string {
+	return render(build(node))
+}
+
+// EBNF 按规则的定义顺序输出 EBNF 文本
+func EBNF(g *grammar.Grammar) string {
+	return g.EBNF()
+}
+
+const style = `body { font-family: sans-serif; }
+svg.railroad path { stroke-width: 2; stroke: #333; fill: none; }
+svg.railroad text { font: 13px monospace; text-anchor: middle; }
+svg.railroad .terminal rect { fill: #dfd; stroke: #333; stroke-width: 2; }
+svg.railroad .nonterminal rect { fill: #ddf; stroke: #333; stroke-width: 2; }
+svg.railroad .special rect { fill: #eee; stroke: #333; stroke-width: 2; stroke-dasharray: 4 2; }
+svg.railroad .group rect { fill: none; stroke: #999; stroke-dasharray: 4 2; }
+svg.railroad .group text { text-anchor: start; fill: #666; }
+pre.ebnf { background: #f6f6f6; padding: 1em; }
+`
+
+// WriteHTML 将整个文法写成一个 HTML 页面，每条规则包含标题、铁路图和对应的 EBNF ，
+// 页面最后附上完整的 EBNF 列表。
+func WriteHTML(w io.Writer, title string, g *grammar.Grammar) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n",
+		html.EscapeString(title), style)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	for _, name := range g.Names() {
+		rule, _ := g.Rule(name)
+		fmt.Fprintf(&b, "<h2 id=\"%s\">%s</h2>\n", anchor(name), html.EscapeString(name))
+		fmt.Fprintf(&b, "%s\n", SVG(rule))
+		fmt.Fprintf(&b, "<pre class=\"ebnf\">%s ::= %s</pre>\n", html.EscapeString(name), html.EscapeString(rule.String()))
+	}
+	fmt.Fprintf(&b, "<h2>EBNF</h2>\n<pre class=\"ebnf\">%s</pre>\n</body>\n</html>\n", html.EscapeString(g.EBNF()))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HTML 与 WriteHTML 相同，但是直接返回页面文本
+func HTML(title string, g *grammar.Grammar) string {
+	var b strings.Builder
+	WriteHTML(&b, title, g)
+	return b.String()
+}
+
+func anchor(name string) string {
+	return "rule-" + strings.Replace(html.EscapeString(name), " ", "-", -1)
+}
+
+// build 将语法结点转换为铁路图部件，Try 和 Bind 是透明的
+func build(n *grammar.Node) diagram {
+	switch n.Kind {
+	case grammar.KindChr, grammar.KindStr, grammar.KindNChr, grammar.KindRuneOf,
+		grammar.KindRuneNone, grammar.KindOne, grammar.KindEOF, grammar.KindFail:
+		return box{class: "terminal", text: n.String()}
+	case grammar.KindRuneP, grammar.KindPrim:
+		return box{class: "special", text: n.Text}
+	case grammar.KindRef:
+		return box{class: "nonterminal", text: n.Text, href: "#" + anchor(n.Text)}
+	case grammar.KindReturn:
+		return skip{}
+	case grammar.KindSeq, grammar.KindThen, grammar.KindOver:
+		if len(n.Children) == 0 {
+			return skip{}
+		}
+		return sequence(builds(n.Children))
+	case grammar.KindChoice:
+		if len(n.Children) == 0 {
+			return box{class: "terminal", text: "<fail>"}
+		}
+		return choice(builds(n.Children))
+	case grammar.KindTry, grammar.KindBind:
+		return build(n.Children[0])
+	case grammar.KindMany:
+		return choice{loop{build(n.Children[0]), skip{}}, skip{}}
+	case grammar.KindMany1:
+		return loop{build(n.Children[0]), skip{}}
+	case grammar.KindOption:
+		return choice{build(n.Children[0]), skip{}}
+	case grammar.KindSepBy1:
+		return loop{build(n.Children[0]), build(n.Children[1])}
+	case grammar.KindSepBy:
+		return choice{loop{build(n.Children[0]), build(n.Children[1])}, skip{}}
+	case grammar.KindAhead:
+		return group{"followed by", build(n.Children[0])}
+	case grammar.KindNot:
+		return group{"not followed by", build(n.Children[0])}
+	}
+	return box{class: "special", text: n.Kind.String()}
+}
+
+func builds(nodes []*grammar.Node) []diagram {
+	re := make([]diagram, len(nodes))
+	for i, node := range nodes {
+		re[i] = build(node)
+	}
+	return re
+}
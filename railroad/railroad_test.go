@@ -0,0 +1,61 @@
+package railroad
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/Dwarfartisan/goparsec2/grammar"
+)
+
+func listGrammar() *grammar.Grammar {
+	g := grammar.New()
+	g.Define("list", grammar.Seq(grammar.Chr('['), grammar.SepBy(grammar.Ref("item"), grammar.Chr(',')), grammar.Chr(']')))
+	g.Define("item", grammar.Choice(grammar.Try(grammar.Ref("number")), grammar.Ref("list"), grammar.Not(grammar.Str("<>"))))
+	g.Define("number", grammar.Seq(grammar.Option(nil, grammar.Chr('-')), grammar.Many1(grammar.RuneP("digit", unicode.IsDigit))))
+	return g
+}
+
+func TestSVGWellFormed(t *testing.T) {
+	g := listGrammar()
+	for _, name := range g.Names() {
+		rule, _ := g.Rule(name)
+		svg := SVG(rule)
+		decoder := xml.NewDecoder(strings.NewReader(svg))
+		for {
+			_, err := decoder.Token()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("rule %s: %v\n%s", name, err, svg)
+			}
+		}
+	}
+}
+
+func TestSVGContent(t *testing.T) {
+	g := listGrammar()
+	rule, _ := g.Rule("item")
+	svg := SVG(rule)
+	for _, expect := range []string{`href="#rule-number"`, `href="#rule-list"`, "not followed by", "&#34;&lt;&gt;&#34;"} {
+		if !strings.Contains(svg, expect) {
+			t.Fatalf("Expect %s in %s", expect, svg)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	g := listGrammar()
+	page := HTML("List", g)
+	for _, expect := range []string{`<h2 id="rule-list">list</h2>`, "number ::= &#34;-&#34;? &lt;digit&gt;+", "<svg"} {
+		if !strings.Contains(page, expect) {
+			t.Fatalf("Expect %s in %s", expect, page)
+		}
+	}
+	if EBNF(g) != g.EBNF() {
+		t.Fatalf("Expect EBNF to be the grammar listing")
+	}
+}
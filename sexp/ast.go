@@ -0,0 +1,125 @@
+package sexp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Span 是结点在源文本中的范围，以 rune 计的偏移， End 不包含在内
+type Span struct {
+	Start int
+	End   int
+}
+
+// Node 是 S 表达式的语法树结点
+type Node interface {
+	// Span 返回结点在源文本中的范围
+	Span() Span
+	// String 返回结点的规范文本形式
+	String() string
+}
+
+// Symbol 是符号
+type Symbol struct {
+	Name string
+	Pos  Span
+}
+
+// Int 是整数
+type Int struct {
+	Value int64
+	Pos   Span
+}
+
+// Float 是浮点数
+type Float struct {
+	Value float64
+	Pos   Span
+}
+
+// String 是字符串，Value 是转义处理之后的内容
+type String struct {
+	Value string
+	Pos   Span
+}
+
+// Bool 是 #t 和 #f
+type Bool struct {
+	Value bool
+	Pos   Span
+}
+
+// List 是括号包围的列表
+type List struct {
+	Items []Node
+	Pos   Span
+}
+
+// Quote 是 'x 、 `x 、 ,x 和 ,@x 这几种引用语法糖， Name 分别为 quote 、 quasiquote 、
+// unquote 和 unquote-splicing
+type Quote struct {
+	Name string
+	Expr Node
+	Pos  Span
+}
+
+// Span 返回结点在源文本中的范围
+func (n *Symbol) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *Int) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *Float) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *String) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *Bool) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *List) Span() Span { return n.Pos }
+
+// Span 返回结点在源文本中的范围
+func (n *Quote) Span() Span { return n.Pos }
+
+func (n *Symbol) String() string { return n.Name }
+
+func (n *Int) String() string { return strconv.FormatInt(n.Value, 10) }
+
+func (n *Float) String() string {
+	re := strconv.FormatFloat(n.Value, 'g', -1, 64)
+	if !strings.ContainsAny(re, ".eIN") {
+		re += ".0"
+	}
+	return re
+}
+
+func (n *String) String() string { return strconv.Quote(n.Value) }
+
+func (n *Bool) String() string {
+	if n.Value {
+		return "#t"
+	}
+	return "#f"
+}
+
+func (n *List) String() string {
+	items := make([]string, len(n.Items))
+	for i, item := range n.Items {
+		items[i] = item.String()
+	}
+	return "(" + strings.Join(items, " ") + ")"
+}
+
+var quotePrefixes = map[string]string{
+	"quote":            "'",
+	"quasiquote":       "`",
+	"unquote":          ",",
+	"unquote-splicing": ",@",
+}
+
+func (n *Quote) String() string {
+	return quotePrefixes[n.Name] + n.Expr.String()
+}
@@ -0,0 +1,290 @@
+// Package sexp 是基于 goP2 的 S 表达式读取器，支持符号、整数、浮点数、字符串、 #t/#f 、
+// 列表、 ' ` , ,@ 引用语法糖、 ; 行注释、可以嵌套的 #| |# 块注释以及 #; 表达式注释。
+//
+// 读取的结果是带有源码范围的语法树，见 Node 。
+package sexp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// ParseError 是带有行列位置的读取错误，行列都从 1 开始， Offset 是以 rune 计的偏移
+type ParseError struct {
+	Line    int
+	Column  int
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("sexp: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// LineColumn 将以 rune 计的偏移转换为从 1 开始的行列位置
+func LineColumn(src string, offset int) (line, column int) {
+	at := goP2.LineCol([]rune(src), offset)
+	return at.Line, at.Column
+}
+
+func parseError(src string, err error) *ParseError {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return &ParseError{1, 1, 0, err.Error()}
+	}
+	line, col := LineColumn(src, e.Pos)
+	return &ParseError{line, col, e.Pos, e.Message}
+}
+
+// peek 返回下一个字符但是不消费它，到达结尾时返回 false
+func peek(state goP2.State) (rune, bool) {
+	pos := state.Pos()
+	x, err := state.Next()
+	if err != nil {
+		return 0, false
+	}
+	state.SeekTo(pos)
+	r, ok := x.(rune)
+	return r, ok
+}
+
+// isDelimiter 判断 r 是否结束一个符号或者数字
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("()\"';`,", r)
+}
+
+var lineComment = goP2.Chr(';').Then(goP2.Skip(goP2.NChr('\n')))
+
+// blockComment 解析 #| |# 块注释，块注释可以嵌套
+var blockComment = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Str("#|").Exec(state)
+	depth := 1
+	for depth > 0 {
+		if _, err := goP2.Try(goP2.Str("|#")).Parse(state); err == nil {
+			depth--
+			continue
+		}
+		if _, err := goP2.Try(goP2.Str("#|")).Parse(state); err == nil {
+			depth++
+			continue
+		}
+		if _, err := state.Next(); err != nil {
+			panic(state.Trap("unterminated block comment"))
+		}
+	}
+	return nil
+})
+
+// datumComment 解析 #; 之后的一个表达式并将其丢弃
+var datumComment = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Str("#;").Exec(state)
+	return Expr.Exec(state)
+})
+
+// Spacing 跳过空白和各种注释
+var Spacing = goP2.Do(func(state goP2.State) interface{} {
+	for {
+		r, ok := peek(state)
+		switch {
+		case !ok:
+			return nil
+		case unicode.IsSpace(r):
+			state.Next()
+		case r == ';':
+			lineComment.Exec(state)
+		case r == '#':
+			if _, err := goP2.Ahead(goP2.Str("#|")).Parse(state); err == nil {
+				blockComment.Exec(state)
+			} else if _, err := goP2.Ahead(goP2.Str("#;")).Parse(state); err == nil {
+				datumComment.Exec(state)
+			} else {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+})
+
+// Expr 读取一个表达式，表达式之前的空白和注释会被跳过，结果为 Node
+var Expr goP2.P
+
+// Forms 读取直到输入结束的全部表达式，结果为 []Node
+var Forms = goP2.Do(func(state goP2.State) interface{} {
+	re := []Node{}
+	for {
+		Spacing.Exec(state)
+		if _, ok := peek(state); !ok {
+			return re
+		}
+		re = append(re, Expr.Exec(state).(Node))
+	}
+})
+
+func init() {
+	Expr = goP2.Do(func(state goP2.State) interface{} {
+		Spacing.Exec(state)
+		return expr(state)
+	})
+}
+
+func expr(state goP2.State) Node {
+	start := state.Pos()
+	r, ok := peek(state)
+	if !ok {
+		panic(state.Trap("unexpected end of input"))
+	}
+	switch r {
+	case '(':
+		return list(state)
+	case ')':
+		panic(state.Trap("unexpected ')'"))
+	case '"':
+		return &String{str(state), Span{start, state.Pos()}}
+	case '\'', '`', ',':
+		state.Next()
+		name := map[rune]string{'\'': "quote", '`': "quasiquote", ',': "unquote"}[r]
+		if r == ',' {
+			if _, err := goP2.Try(goP2.Chr('@')).Parse(state); err == nil {
+				name = "unquote-splicing"
+			}
+		}
+		e := Expr.Exec(state).(Node)
+		return &Quote{name, e, Span{start, state.Pos()}}
+	}
+	return atom(state)
+}
+
+func list(state goP2.State) Node {
+	start := state.Pos()
+	goP2.Chr('(').Exec(state)
+	items := []Node{}
+	for {
+		Spacing.Exec(state)
+		r, ok := peek(state)
+		if !ok {
+			state.SeekTo(start)
+			panic(state.Trap("unclosed '('"))
+		}
+		if r == ')' {
+			state.Next()
+			return &List{items, Span{start, state.Pos()}}
+		}
+		items = append(items, expr(state))
+	}
+}
+
+var escapes = map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '0': 0, '\\': '\\', '"': '"'}
+
+func str(state goP2.State) string {
+	start := state.Pos()
+	goP2.Chr('"').Exec(state)
+	var b strings.Builder
+	for {
+		x, err := state.Next()
+		if err != nil {
+			state.SeekTo(start)
+			panic(state.Trap("unterminated string"))
+		}
+		r := x.(rune)
+		switch r {
+		case '"':
+			return b.String()
+		case '\\':
+			pos := state.Pos()
+			x, err := state.Next()
+			if err != nil {
+				state.SeekTo(start)
+				panic(state.Trap("unterminated string"))
+			}
+			e := x.(rune)
+			if c, ok := escapes[e]; ok {
+				b.WriteRune(c)
+				continue
+			}
+			if e == 'u' {
+				code, err := goP2.Times(4, goP2.RuneOf("0123456789abcdefABCDEF")).Parse(state)
+				if err == nil {
+					v, _ := strconv.ParseUint(goP2.ToString(code), 16, 32)
+					b.WriteRune(rune(v))
+					continue
+				}
+			}
+			state.SeekTo(pos - 1)
+			panic(state.Trap("invalid escape in string"))
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// atom 读取符号、数字或者 #t/#f ，它们都延伸到下一个分隔符为止
+func atom(state goP2.State) Node {
+	start := state.Pos()
+	var b strings.Builder
+	for {
+		r, ok := peek(state)
+		if !ok || isDelimiter(r) {
+			break
+		}
+		state.Next()
+		b.WriteRune(r)
+	}
+	text := b.String()
+	span := Span{start, state.Pos()}
+	if strings.HasPrefix(text, "#") {
+		switch text {
+		case "#t", "#true":
+			return &Bool{true, span}
+		case "#f", "#false":
+			return &Bool{false, span}
+		}
+		state.SeekTo(start)
+		panic(state.Trap("unknown syntax %s", text))
+	}
+	if numeric(text) {
+		if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return &Int{v, span}
+		}
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			return &Float{v, span}
+		}
+		state.SeekTo(start)
+		panic(state.Trap("invalid number %s", text))
+	}
+	return &Symbol{text, span}
+}
+
+// numeric 判断 text 是否具有数字的形式，即去掉符号之后以数字或者小数点加数字开头，
+// 这样 + 、 - 、 ... 、 inf 之类的文本仍然是符号
+func numeric(text string) bool {
+	text = strings.TrimLeft(text, "+-")
+	if strings.HasPrefix(text, ".") {
+		text = text[1:]
+	}
+	return text != "" && '0' <= text[0] && text[0] <= '9'
+}
+
+// Read 读取 src 中的全部表达式，错误总是 *ParseError
+func Read(src string) ([]Node, error) {
+	state := goP2.BasicStateFromText(src)
+	re, err := Forms.Parse(&state)
+	if err != nil {
+		return nil, parseError(src, err)
+	}
+	return re.([]Node), nil
+}
+
+// ReadOne 读取 src 中唯一的一个表达式
+func ReadOne(src string) (Node, error) {
+	state := goP2.BasicStateFromText(src)
+	re, err := Expr.Over(Spacing).Over(goP2.EOF).Parse(&state)
+	if err != nil {
+		return nil, parseError(src, err)
+	}
+	return re.(Node), nil
+}
@@ -0,0 +1,107 @@
+package sexp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	src := `; rules
+(define (check x)
+  #| block #| nested |# |#
+  (if (> x 1.5) "big\n" 'small))
+#;(ignored form)
+` + "`(a ,b ,@c)" + ` #t -7`
+	forms, err := Read(src)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	expect := []string{
+		`(define (check x) (if (> x 1.5) "big\n" 'small))`,
+		"`(a ,b ,@c)",
+		"#t",
+		"-7",
+	}
+	if len(forms) != len(expect) {
+		t.Fatalf("Expect %d forms but %v", len(expect), forms)
+	}
+	for i, form := range forms {
+		if form.String() != expect[i] {
+			t.Errorf("Expect %s but %s", expect[i], form)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	form, err := ReadOne(`(+ 1 2.5 -x "s" ...)`)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	items := form.(*List).Items
+	if s, ok := items[0].(*Symbol); !ok || s.Name != "+" {
+		t.Errorf("Expect symbol + but %#v", items[0])
+	}
+	if n, ok := items[1].(*Int); !ok || n.Value != 1 {
+		t.Errorf("Expect int 1 but %#v", items[1])
+	}
+	if n, ok := items[2].(*Float); !ok || n.Value != 2.5 {
+		t.Errorf("Expect float 2.5 but %#v", items[2])
+	}
+	if s, ok := items[3].(*Symbol); !ok || s.Name != "-x" {
+		t.Errorf("Expect symbol -x but %#v", items[3])
+	}
+	if s, ok := items[4].(*String); !ok || s.Value != "s" {
+		t.Errorf("Expect string s but %#v", items[4])
+	}
+	if s, ok := items[5].(*Symbol); !ok || s.Name != "..." {
+		t.Errorf("Expect symbol ... but %#v", items[5])
+	}
+}
+
+func TestSpan(t *testing.T) {
+	src := "(a\n  'bc \"d\")"
+	form, err := ReadOne(src)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if form.Span() != (Span{0, 13}) {
+		t.Fatalf("Expect list span {0 13} but %v", form.Span())
+	}
+	quote := form.(*List).Items[1].(*Quote)
+	if quote.Span() != (Span{5, 8}) || quote.Expr.Span() != (Span{6, 8}) {
+		t.Fatalf("Expect quote span {5 8} and {6 8} but %v and %v", quote.Span(), quote.Expr.Span())
+	}
+	if line, col := LineColumn(src, quote.Span().Start); line != 2 || col != 3 {
+		t.Fatalf("Expect line 2, column 3 but %d, %d", line, col)
+	}
+	str := form.(*List).Items[2]
+	if got := string([]rune(src)[str.Span().Start:str.Span().End]); got != `"d"` {
+		t.Fatalf("Expect span text \"d\" but %s", got)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	for _, c := range []struct {
+		src     string
+		line    int
+		column  int
+		message string
+	}{
+		{"(a\n  (b c)", 1, 1, "unclosed"},
+		{"(a))", 1, 4, "unexpected ')'"},
+		{"\n\"abc", 2, 1, "unterminated string"},
+		{"#| open", 1, 8, "unterminated block comment"},
+		{"(#x)", 1, 2, "unknown syntax"},
+		{"12ab", 1, 1, "invalid number"},
+	} {
+		_, err := Read(c.src)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: Expect *ParseError but %v", c.src, err)
+			continue
+		}
+		if e.Line != c.line || e.Column != c.column || !strings.Contains(e.Message, c.message) {
+			t.Errorf("%q: Expect %s at %d:%d but %v", c.src, c.message, c.line, c.column, e)
+		}
+	}
+}
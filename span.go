@@ -0,0 +1,84 @@
+package goP2
+
+// Span 是一段输入的范围， End 不包含在内。 From 和 To 是 Start 和 End 处的行列位置，
+// 只有 State 或者它包装的 State 实现了 Position 方法（例如 IndentState）时才会填写，否则为零值
+type Span struct {
+	Start int
+	End   int
+	From  SourcePos
+	To    SourcePos
+}
+
+// Spanned 是 WithSpan 的结果
+type Spanned struct {
+	Value interface{}
+	Span  Span
+}
+
+// Node 是带有源码范围的 AST 结点。结点类型嵌入 NodeBase 即可实现 Node ，之后用 WithNode 解析
+type Node interface {
+	NodeSpan() Span
+	SetNodeSpan(span Span)
+}
+
+// NodeBase 用于嵌入 AST 结点类型，为结点的指针类型实现 Node
+type NodeBase struct {
+	Span Span
+}
+
+// NodeSpan 返回结点的源码范围
+func (n NodeBase) NodeSpan() Span {
+	return n.Span
+}
+
+// SetNodeSpan 设置结点的源码范围
+func (n *NodeBase) SetNodeSpan(span Span) {
+	n.Span = span
+}
+
+// spanOf 运行 p 并记录它消费的范围
+func spanOf(p P, state State) (interface{}, Span, error) {
+	located, ok := findLocator(state)
+	var span Span
+	span.Start = state.Pos()
+	if ok {
+		span.From = located.Position()
+	}
+	re, err := p(state)
+	if err != nil {
+		return nil, span, err
+	}
+	span.End = state.Pos()
+	if ok {
+		span.To = located.Position()
+	}
+	return re, span, nil
+}
+
+// WithSpan 运行 p ，结果为 Spanned ，包含 p 的结果和它消费的范围。
+// 在 Bind 的回调中拿不到 State ，需要位置时用 WithSpan 包装被绑定的算子
+func WithSpan(p P) P {
+	return func(state State) (interface{}, error) {
+		re, span, err := spanOf(p, state)
+		if err != nil {
+			return nil, err
+		}
+		return Spanned{re, span}, nil
+	}
+}
+
+// WithNode 运行 p 并把它消费的范围写入结果结点，结果仍然是 p 的结果。 p 的结果必须实现 Node
+func WithNode(p P) P {
+	return func(state State) (interface{}, error) {
+		re, span, err := spanOf(p, state)
+		if err != nil {
+			return nil, err
+		}
+		node, ok := re.(Node)
+		if !ok {
+			return nil, state.Trap("Expect a Node but %T", re)
+		}
+		node.SetNodeSpan(span)
+		return node, nil
+	}
+}
@@ -0,0 +1,60 @@
+package goP2
+
+import (
+	"testing"
+)
+
+// ident 是带有源码范围的标识符结点
+type ident struct {
+	NodeBase
+	Name string
+}
+
+var identNode = WithNode(Many1(RuneOf("abcxyz")).Bind(func(x interface{}) P {
+	return Return(&ident{Name: ToString(x)})
+}))
+
+func TestWithSpan(t *testing.T) {
+	state := BasicStateFromText("  abc;")
+	re, err := Skip(Chr(' ')).Then(WithSpan(Many1(RuneOf("abc")).Bind(ReturnString))).Parse(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := re.(Spanned)
+	if s.Value != "abc" || s.Span.Start != 2 || s.Span.End != 5 {
+		t.Fatalf("Expect abc [2,5) but %v", s)
+	}
+	if s.Span.From != (SourcePos{}) {
+		t.Fatalf("Expect no line and column without Position but %v", s.Span.From)
+	}
+}
+
+func TestWithNode(t *testing.T) {
+	base := BasicStateFromText("x\n  yz;")
+	state := NewIndentState(&base)
+	re, err := identNode.Then(Skip(RuneOf(" \n"))).Then(identNode).Parse(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := re.(Node)
+	expect := Span{Start: 4, End: 6, From: SourcePos{2, 3}, To: SourcePos{2, 5}}
+	if node.NodeSpan() != expect || re.(*ident).Name != "yz" {
+		t.Fatalf("Expect yz %v but %v %v", expect, re.(*ident).Name, node.NodeSpan())
+	}
+
+	// 包装在 Sized 和 UserState 之内时沿着 Unwrap 链取得行列位置
+	base = BasicStateFromText("x\n  yz;")
+	wrapped := NewUserState(NewIndentState(&base), nil)
+	re, err = Times(4, One).Then(Sized(2, identNode)).Parse(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if span := re.(Node).NodeSpan(); span != expect {
+		t.Fatalf("Expect %v but %v", expect, span)
+	}
+
+	plain := BasicStateFromText("a")
+	if _, err := WithNode(One).Parse(&plain); err == nil {
+		t.Fatalf("Expect error for a result that is not a Node")
+	}
+}
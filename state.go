@@ -13,6 +13,21 @@ type State interface {
 	Rollback(int)
 }
 
+// findState 沿着 Unwrap 链查找第一个满足 match 的 State ，使得 Sized 、 UserState 之类的包装
+// 不影响需要特定 State 的算子
+func findState(state State, match func(State) bool) (State, bool) {
+	for s := state; ; {
+		if match(s) {
+			return s, true
+		}
+		w, ok := s.(interface{ Unwrap() State })
+		if !ok {
+			return nil, false
+		}
+		s = w.Unwrap()
+	}
+}
+
 // BasicState 实现最基本的 State 操作
 type BasicState struct {
 	buffer []interface{}
@@ -62,7 +77,7 @@ func (state *BasicState) SeekTo(pos int) bool {
 // Next 实现迭代逻辑
 func (state *BasicState) Next() (interface{}, error) {
 	if state.index == len(state.buffer) {
-		return nil, state.Trap("eof")
+		return nil, Raise(state, "eof")
 	}
 	re := state.buffer[state.index]
 	state.index++
@@ -71,7 +86,7 @@ func (state *BasicState) Next() (interface{}, error) {
 
 // Trap 是构造错误信息的辅助函数，它传递错误的位置，并提供字符串格式化功能
 func (state *BasicState) Trap(message string, args ...interface{}) error {
-	return Error{state.index, fmt.Sprintf(message, args...)}
+	return Error{Pos: state.index, Message: fmt.Sprintf(message, args...)}
 }
 
 // Begin 开始一个事务并返回事务号，State 的 Begin 总是记录比较靠后的位置。
@@ -97,10 +112,24 @@ func (state *BasicState) Rollback(tran int) {
 	}
 }
 
-// Error 实现基本的错误信息结构
+// Error 实现基本的错误信息结构。
+// 注意： Expected 、 Unexpected 和 Args 是切片，所以 Error 不能再用 == 比较，也不能作为 map 的键，
+// 需要比较时使用 Pos 、 Message 等字段或者 reflect.DeepEqual
 type Error struct {
 	Pos     int
 	Message string
+	// End 是出错范围的结尾，不包含在内，不大于 Pos 时错误只指向 Pos 一个位置
+	End int
+	// Expected 是出错位置上期望的内容， Unexpected 是实际遇到的内容，由 Label 、 Unexpected 填写，
+	// Choice 会合并各个分支的期望
+	Expected   []string
+	Unexpected []string
+	// Code 是错误代码， Args 是格式化信息的参数，由 Raise 填写，用 Catalog 可以把信息翻译为其它语言。
+	// 用 Trap 构造的错误没有错误代码
+	Code string
+	Args []interface{}
+	// Cut 为 true 时错误发生在 Cut 之内， Try 、 Choice 和重复算子不再回溯
+	Cut bool
 }
 
 func (e Error) Error() string {
@@ -0,0 +1,388 @@
+// Package tagparse 根据结构体字段上的 parse 标签构造 goP2 算子，直接将输入解析到结构体中。
+//
+// 每个带 parse 标签的导出字段按照声明顺序依次匹配，标签内容是由空白分隔的若干项：
+//
+//	'text' 或 "text"  匹配字面量
+//	@Name             用名为 Name 的终结符匹配，并将结果写入字段
+//	@@                按字段自身的类型（结构体）递归匹配，并将结果写入字段
+//
+// 切片字段会重复匹配整个标签（Many），如果同时给出 sep 标签，则以该字面量分隔（SepBy）；
+// 指针字段以及带有 optional:"true" 标签的字段是可选的（Option）。没有捕获项的 bool 字段
+// 在标签匹配成功时被设置为 true 。
+// 每一项匹配之后都会跳过空白。以字母、数字或者下划线结尾的字面量是关键字，之后不能紧跟标识符字符。
+package tagparse
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// Builder 保存终结符定义，并缓存已经构造过的结构体算子
+type Builder struct {
+	terminals  map[string]goP2.P
+	whitespace goP2.P
+	types      map[reflect.Type]*goP2.P
+}
+
+// NewBuilder 构造一个 Builder ，预置 Ident 、 Int 、 Float 、 String 和 Value 终结符
+func NewBuilder() *Builder {
+	b := &Builder{
+		terminals:  make(map[string]goP2.P),
+		whitespace: goP2.Skip(goP2.Space),
+		types:      make(map[reflect.Type]*goP2.P),
+	}
+	b.Terminal("Ident", Ident)
+	b.Terminal("Int", goP2.Int)
+	b.Terminal("Float", goP2.Try(goP2.P(goP2.Float)))
+	b.Terminal("String", String)
+	b.Terminal("Value", Value)
+	return b
+}
+
+// Terminal 定义或者替换一个终结符，标签中可以用 @name 引用它
+func (b *Builder) Terminal(name string, p goP2.P) *Builder {
+	b.terminals[name] = p
+	b.types = make(map[reflect.Type]*goP2.P)
+	return b
+}
+
+// Whitespace 设置每一项之后跳过的内容，默认跳过所有空白字符
+func (b *Builder) Whitespace(p goP2.P) *Builder {
+	b.whitespace = p
+	b.types = make(map[reflect.Type]*goP2.P)
+	return b
+}
+
+// Ident 匹配由字母、数字和下划线组成，并且不以数字开头的标识符
+var Ident = goP2.Do(func(state goP2.State) interface{} {
+	head := goP2.RuneP("identifier", func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r)
+	}).Exec(state).(rune)
+	tail := goP2.Many(goP2.RuneP("identifier", identRune)).Exec(state)
+	return string([]rune{head}) + goP2.ToString(tail)
+})
+
+// identRune 判断 r 是否可以出现在标识符中
+func identRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// literal 匹配字面量。以标识符字符结尾的字面量是关键字，之后不能紧跟标识符字符，
+// 所以 'ports' 不会匹配 portsx 的前缀
+func literal(text string) goP2.P {
+	runes := []rune(text)
+	if len(runes) == 0 || !identRune(runes[len(runes)-1]) {
+		return goP2.Str(text).Then(goP2.Return(nil))
+	}
+	return goP2.Str(text).Then(func(state goP2.State) (interface{}, error) {
+		pos := state.Pos()
+		x, err := state.Next()
+		if err != nil {
+			return nil, nil
+		}
+		state.SeekTo(pos)
+		if r, ok := x.(rune); ok && identRune(r) {
+			return nil, state.Trap("Expect a word boundary after %q but %q", text, r)
+		}
+		return nil, nil
+	})
+}
+
+// String 匹配一个双引号字符串，支持 Go 风格的转义，返回去掉引号之后的内容
+var String = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Chr('"').Exec(state)
+	body := goP2.Many(goP2.Choice(
+		goP2.Try(goP2.Chr('\\').Then(goP2.One).Bind(func(x interface{}) goP2.P {
+			return goP2.Return(string([]rune{'\\', x.(rune)}))
+		})),
+		goP2.RuneNone("\"\\\n").Bind(func(x interface{}) goP2.P {
+			return goP2.Return(string([]rune{x.(rune)}))
+		}),
+	)).Exec(state).([]interface{})
+	goP2.Chr('"').Exec(state)
+	var buf strings.Builder
+	buf.WriteRune('"')
+	for _, part := range body {
+		buf.WriteString(part.(string))
+	}
+	buf.WriteRune('"')
+	re, err := strconv.Unquote(buf.String())
+	if err != nil {
+		panic(state.Trap("invalid string %s: %v", buf.String(), err))
+	}
+	return re
+})
+
+// Value 匹配字符串、数字或者标识符，返回它们的文本
+var Value = goP2.Choice(
+	goP2.Try(String),
+	goP2.Try(goP2.P(goP2.Float)),
+	goP2.Try(goP2.P(goP2.Int)),
+	Ident,
+)
+
+// item 是标签中的一项
+type item struct {
+	literal string
+	capture string
+}
+
+var tagSpace = goP2.Skip(goP2.Space)
+
+func tagQuoted(q rune) goP2.P {
+	return goP2.Between(goP2.Chr(q), goP2.Chr(q), goP2.Many(goP2.NChr(q))).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(item{literal: goP2.ToString(x)})
+	})
+}
+
+var tagItem = goP2.Choice(
+	goP2.Try(tagQuoted('\'')),
+	goP2.Try(tagQuoted('"')),
+	goP2.Try(goP2.Str("@@")).Then(goP2.Return(item{capture: "@"})),
+	goP2.Chr('@').Then(Ident).Bind(func(x interface{}) goP2.P {
+		return goP2.Return(item{capture: x.(string)})
+	}),
+).Over(tagSpace)
+
+var tagItems = tagSpace.Then(goP2.Many1(tagItem)).Over(goP2.EOF)
+
+func parseTag(tag string) ([]item, error) {
+	state := goP2.BasicStateFromText(tag)
+	re, err := tagItems.Parse(&state)
+	if err != nil {
+		return nil, err
+	}
+	data := re.([]interface{})
+	items := make([]item, len(data))
+	for i, x := range data {
+		items[i] = x.(item)
+	}
+	return items, nil
+}
+
+// Build 构造解析到 v 的类型的算子，v 是结构体或者结构体指针，算子返回新的结构体指针
+func (b *Builder) Build(v interface{}) (goP2.P, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tagparse: expect a struct but %v", reflect.TypeOf(v))
+	}
+	return b.build(t)
+}
+
+// Parse 解析整个文本到 v 指向的结构体中，文本开头和结尾的空白会被忽略
+func (b *Builder) Parse(text string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("tagparse: expect a non-nil struct pointer but %T", v)
+	}
+	p, err := b.Build(v)
+	if err != nil {
+		return err
+	}
+	state := goP2.BasicStateFromText(text)
+	re, err := b.whitespace.Then(p).Over(goP2.EOF).Parse(&state)
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(reflect.ValueOf(re).Elem())
+	return nil
+}
+
+// build 构造结构体算子，通过间接引用支持递归的结构体
+func (b *Builder) build(t reflect.Type) (goP2.P, error) {
+	ref, ok := b.types[t]
+	if !ok {
+		ref = new(goP2.P)
+		b.types[t] = ref
+		p, err := b.structP(t)
+		if err != nil {
+			delete(b.types, t)
+			return nil, err
+		}
+		*ref = p
+	}
+	return func(state goP2.State) (interface{}, error) {
+		return (*ref)(state)
+	}, nil
+}
+
+type field struct {
+	index int
+	p     goP2.P
+}
+
+func (b *Builder) structP(t reflect.Type) (goP2.P, error) {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("parse")
+		if !ok || sf.PkgPath != "" {
+			continue
+		}
+		p, err := b.fieldP(sf, tag)
+		if err != nil {
+			return nil, fmt.Errorf("tagparse: %s.%s: %v", t.Name(), sf.Name, err)
+		}
+		fields = append(fields, field{i, p})
+	}
+	return func(state goP2.State) (interface{}, error) {
+		v := reflect.New(t)
+		for _, f := range fields {
+			captures, err := f.p(state)
+			if err != nil {
+				return nil, err
+			}
+			if err := assign(v.Elem().Field(f.index), captures.([]interface{})); err != nil {
+				return nil, state.Trap("%v", err)
+			}
+		}
+		return v.Interface(), nil
+	}, nil
+}
+
+// fieldP 构造字段的算子，算子返回本字段捕获的所有值
+func (b *Builder) fieldP(sf reflect.StructField, tag string) (goP2.P, error) {
+	items, err := parseTag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag %q: %v", tag, err)
+	}
+	elem := sf.Type
+	repeated := elem.Kind() == reflect.Slice
+	optional := elem.Kind() == reflect.Ptr || sf.Tag.Get("optional") == "true"
+	for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	ps := make([]goP2.P, 0, len(items))
+	captured := false
+	for _, it := range items {
+		captured = captured || it.capture != ""
+		var p goP2.P
+		switch it.capture {
+		case "":
+			p = literal(it.literal)
+		case "@":
+			if elem.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("@@ requires a struct type but %v", elem)
+			}
+			p, err = b.build(elem)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			term, ok := b.terminals[it.capture]
+			if !ok {
+				return nil, fmt.Errorf("undefined terminal %s", it.capture)
+			}
+			p = term
+		}
+		ps = append(ps, p.Over(b.whitespace))
+	}
+	once := goP2.Union(ps...)
+	if !captured && elem.Kind() == reflect.Bool {
+		once = once.Then(goP2.Return([]interface{}{true}))
+	}
+	if repeated {
+		var items goP2.P
+		if sep, ok := sf.Tag.Lookup("sep"); ok {
+			items = goP2.SepBy(once, goP2.Str(sep).Over(b.whitespace))
+		} else {
+			items = goP2.Many(once)
+		}
+		return items.Bind(func(x interface{}) goP2.P {
+			var captures []interface{}
+			for _, group := range x.([]interface{}) {
+				captures = append(captures, group.([]interface{})...)
+			}
+			return goP2.Return(captures)
+		}), nil
+	}
+	if optional {
+		return goP2.Option([]interface{}{}, goP2.Try(once)), nil
+	}
+	return once, nil
+}
+
+// assign 将捕获的值写入字段，切片字段追加所有值，其他字段使用最后一个值
+func assign(v reflect.Value, captures []interface{}) error {
+	if len(captures) == 0 {
+		return nil
+	}
+	if v.Kind() == reflect.Slice {
+		for _, x := range captures {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := convert(elem, x); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+		return nil
+	}
+	return convert(v, captures[len(captures)-1])
+}
+
+// convert 将 x 转换为 v 的类型，字符串会按照目标类型解析
+func convert(v reflect.Value, x interface{}) error {
+	xv := reflect.ValueOf(x)
+	if v.Kind() == reflect.Ptr {
+		if xv.Type().AssignableTo(v.Type()) {
+			v.Set(xv)
+			return nil
+		}
+		target := reflect.New(v.Type().Elem())
+		if err := convert(target.Elem(), x); err != nil {
+			return err
+		}
+		v.Set(target)
+		return nil
+	}
+	if xv.Kind() == reflect.Ptr && xv.Type().Elem() == v.Type() {
+		xv = xv.Elem()
+	}
+	if xv.Type().AssignableTo(v.Type()) {
+		v.Set(xv)
+		return nil
+	}
+	str := fmt.Sprintf("%v", x)
+	if r, ok := x.(rune); ok {
+		str = string([]rune{r})
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(str, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(str, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		t, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		v.SetBool(t)
+	default:
+		return fmt.Errorf("cannot assign %v to %v", x, v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package tagparse
+
+import (
+	"testing"
+)
+
+type entry struct {
+	Key   string      `parse:"@Ident '='"`
+	Value interface{} `parse:"@Value"`
+}
+
+type section struct {
+	Name    string   `parse:"'[' @Ident ']'"`
+	Ports   []int    `parse:"'ports' '=' @Int" sep:","`
+	Entries []*entry `parse:"@@"`
+}
+
+type config struct {
+	Version *float64  `parse:"'version' @Float"`
+	Debug   bool      `parse:"'debug'" optional:"true"`
+	Globals []entry   `parse:"@@"`
+	Groups  []section `parse:"@@"`
+}
+
+const configText = `
+version 1.5
+name = "demo service"
+retry = 3
+[server]
+ports = 80, ports = 443
+host = localhost
+[client]
+`
+
+func TestParseConfig(t *testing.T) {
+	var cfg config
+	if err := NewBuilder().Parse(configText, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version == nil || *cfg.Version != 1.5 {
+		t.Fatalf("Expect version 1.5 but %v", cfg.Version)
+	}
+	if len(cfg.Globals) != 2 || cfg.Globals[0].Value != "demo service" || cfg.Globals[1].Value != "3" {
+		t.Fatalf("Expect two globals but %+v", cfg.Globals)
+	}
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Name != "server" || cfg.Groups[1].Name != "client" {
+		t.Fatalf("Expect sections server and client but %+v", cfg.Groups)
+	}
+	server := cfg.Groups[0]
+	if len(server.Ports) != 2 || server.Ports[0] != 80 || server.Ports[1] != 443 {
+		t.Fatalf("Expect ports [80 443] but %v", server.Ports)
+	}
+	if len(server.Entries) != 1 || server.Entries[0].Key != "host" {
+		t.Fatalf("Expect entry host but %+v", server.Entries)
+	}
+}
+
+func TestParseOptional(t *testing.T) {
+	var cfg config
+	if err := NewBuilder().Parse("debug a = b", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != nil || !cfg.Debug || len(cfg.Globals) != 1 {
+		t.Fatalf("Expect only debug and one global but %+v", cfg)
+	}
+}
+
+func TestKeywordBoundary(t *testing.T) {
+	var cfg config
+	if err := NewBuilder().Parse("debugger = 1\n[server]\nportsx = 2", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Debug || len(cfg.Globals) != 1 || cfg.Globals[0].Key != "debugger" {
+		t.Fatalf("Expect global debugger but %+v", cfg)
+	}
+	server := cfg.Groups[0]
+	if len(server.Ports) != 0 || len(server.Entries) != 1 || server.Entries[0].Key != "portsx" {
+		t.Fatalf("Expect entry portsx but %+v", server)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var cfg config
+	if err := NewBuilder().Parse("a = b [", &cfg); err == nil {
+		t.Fatalf("Expect a error for unclosed section but %+v", cfg)
+	}
+}
+
+type badTag struct {
+	Name string `parse:"@Unknown"`
+}
+
+func TestBuildError(t *testing.T) {
+	if _, err := NewBuilder().Build(badTag{}); err == nil {
+		t.Fatal("Expect a error for undefined terminal")
+	}
+}
@@ -16,9 +16,9 @@ func Chr(val rune) P {
 			if c == val {
 				return c, nil
 			}
-			return nil, state.Trap("Expect '%v' but '%v'", string([]rune{val}), string([]rune{c}))
+			return nil, Raise(state, "chr", string([]rune{val}), string([]rune{c}))
 		}
-		return nil, state.Trap("Expect a rune '%s' but x is %v", string([]rune{val}), x)
+		return nil, Raise(state, "element-type", "rune", x)
 	}
 }
 
@@ -31,11 +31,11 @@ func NChr(val rune) P {
 		}
 		if c, ok := x.(int32); ok {
 			if c == val {
-				return nil, state.Trap("Expect not '%v' but '%v'", string([]rune{val}), string([]rune{c}))
+				return nil, Raise(state, "not-chr", string([]rune{val}), string([]rune{c}))
 			}
 			return c, nil
 		}
-		return nil, state.Trap("Expect a rune '%s' but x is %v", string([]rune{val}), x)
+		return nil, Raise(state, "element-type", "rune", x)
 	}
 }
 
@@ -53,9 +53,9 @@ func RuneOf(str string) P {
 					return c, nil
 				}
 			}
-			return nil, state.Trap("Expect rune in '%s' but '%s'", str, string([]rune{c}))
+			return nil, Raise(state, "rune-of", str, string([]rune{c}))
 		}
-		return nil, state.Trap("Expect rune in '%s' but x=%v is %t", str, x, x)
+		return nil, Raise(state, "element-type", "rune", x)
 	}
 }
 
@@ -70,12 +70,12 @@ func RuneNone(str string) P {
 		if c, ok := x.(int32); ok {
 			for _, r := range data {
 				if c == r {
-					return nil, state.Trap("Expect rune none of '%s' but '%s'", str, string([]rune{c}))
+					return nil, Raise(state, "rune-none-of", str, string([]rune{c}))
 				}
 			}
 			return c, nil
 		}
-		return nil, state.Trap("Expect rune none of '%s' but x=%v is %t", str, x, x)
+		return nil, Raise(state, "element-type", "rune", x)
 	}
 }
 
@@ -105,9 +105,9 @@ func RuneP(name string, pred func(r rune) bool) P {
 			if pred(r) {
 				return c, nil
 			}
-			return nil, state.Trap("Expect %s but '%v'", name, string([]rune{r}))
+			return nil, Raise(state, "rune-pred", name, string([]rune{r}))
 		}
-		return nil, state.Trap("Expect %s but x=%v is %t", name, x, x)
+		return nil, Raise(state, "element-type", "rune", x)
 	}
 }
 
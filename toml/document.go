@@ -0,0 +1,198 @@
+package toml
+
+import (
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// kind 记录表是如何被定义的，TOML 据此判断重复定义
+type kind int
+
+const (
+	// implicitTable 是表头路径上隐式创建的表，之后还可以用表头定义一次
+	implicitTable kind = iota
+	// explicitTable 是由 [表头] 定义的表
+	explicitTable
+	// dottedTable 是由点分键定义的表
+	dottedTable
+	// arrayOfTables 是由 [[表头]] 定义的表数组
+	arrayOfTables
+	// leaf 是普通的值，包括数组和内联表，它们都不能再扩展
+	leaf
+)
+
+type node struct {
+	kind     kind
+	children map[string]*node
+	items    []*node
+	value    interface{}
+}
+
+func newTable(k kind) *node {
+	return &node{kind: k, children: make(map[string]*node)}
+}
+
+// failAt 将 state 移回 pos 并在该位置报错
+func failAt(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+func joinKey(keys []string) string {
+	return strings.Join(keys, ".")
+}
+
+// walk 沿着表头中除最后一段以外的路径查找子表，不存在的表隐式创建，表数组取最后一个元素
+func (n *node) walk(state goP2.State, pos int, keys []string) *node {
+	current := n
+	for i, key := range keys {
+		child, ok := current.children[key]
+		if !ok {
+			child = newTable(implicitTable)
+			current.children[key] = child
+		}
+		switch child.kind {
+		case arrayOfTables:
+			child = child.items[len(child.items)-1]
+		case leaf:
+			failAt(state, pos, "key %s is not a table", joinKey(keys[:i+1]))
+		}
+		current = child
+	}
+	return current
+}
+
+// define 处理 [keys] 表头，返回被定义的表
+func (n *node) define(state goP2.State, pos int, keys []string) *node {
+	last := len(keys) - 1
+	parent := n.walk(state, pos, keys[:last])
+	child, ok := parent.children[keys[last]]
+	if !ok {
+		child = newTable(explicitTable)
+		parent.children[keys[last]] = child
+		return child
+	}
+	if child.kind != implicitTable {
+		failAt(state, pos, "table %s already defined", joinKey(keys))
+	}
+	child.kind = explicitTable
+	return child
+}
+
+// appendTable 处理 [[keys]] 表头，向表数组追加并返回一个新表
+func (n *node) appendTable(state goP2.State, pos int, keys []string) *node {
+	last := len(keys) - 1
+	parent := n.walk(state, pos, keys[:last])
+	child, ok := parent.children[keys[last]]
+	if !ok {
+		child = &node{kind: arrayOfTables}
+		parent.children[keys[last]] = child
+	} else if child.kind != arrayOfTables {
+		failAt(state, pos, "key %s is not an array of tables", joinKey(keys))
+	}
+	item := newTable(explicitTable)
+	child.items = append(child.items, item)
+	return item
+}
+
+// set 处理 keys = value ，点分键路径上的表只能由点分键创建
+func (n *node) set(state goP2.State, pos int, keys []string, value interface{}) {
+	last := len(keys) - 1
+	current := n
+	for i, key := range keys[:last] {
+		child, ok := current.children[key]
+		if !ok {
+			child = newTable(dottedTable)
+			current.children[key] = child
+		} else if child.kind != dottedTable {
+			failAt(state, pos, "cannot add keys to %s with dotted keys", joinKey(keys[:i+1]))
+		}
+		current = child
+	}
+	if _, ok := current.children[keys[last]]; ok {
+		failAt(state, pos, "duplicate key %s", joinKey(keys))
+	}
+	current.children[keys[last]] = &node{kind: leaf, value: value}
+}
+
+func (n *node) toMap() map[string]interface{} {
+	re := make(map[string]interface{}, len(n.children))
+	for key, child := range n.children {
+		switch child.kind {
+		case leaf:
+			re[key] = child.value
+		case arrayOfTables:
+			items := make([]map[string]interface{}, len(child.items))
+			for i, item := range child.items {
+				items[i] = item.toMap()
+			}
+			re[key] = items
+		default:
+			re[key] = child.toMap()
+		}
+	}
+	return re
+}
+
+// keyval 解析 key = value 并返回键路径和值
+func keyval(state goP2.State) ([]string, interface{}) {
+	keys := key(state)
+	ws.Exec(state)
+	expect(state, '=', "Expect '=' after key")
+	ws.Exec(state)
+	return keys, value(state)
+}
+
+// header 解析 [table] 或者 [[array]] 表头，返回之后的键值对所属的表
+func header(state goP2.State, root *node) *node {
+	pos := state.Pos()
+	if _, err := goP2.Try(goP2.Str("[[")).Parse(state); err == nil {
+		ws.Exec(state)
+		keys := key(state)
+		ws.Exec(state)
+		if _, err := goP2.Try(goP2.Str("]]")).Parse(state); err != nil {
+			panic(state.Trap("Expect ']]' after array of tables header"))
+		}
+		return root.appendTable(state, pos, keys)
+	}
+	goP2.Chr('[').Exec(state)
+	ws.Exec(state)
+	keys := key(state)
+	ws.Exec(state)
+	expect(state, ']', "Expect ']' after table header")
+	return root.define(state, pos, keys)
+}
+
+func document(state goP2.State) interface{} {
+	root := newTable(explicitTable)
+	current := root
+	for {
+		ws.Exec(state)
+		r, ok := peek(state)
+		if !ok {
+			break
+		}
+		switch r {
+		case '#', '\n', '\r':
+		case '[':
+			current = header(state, root)
+		default:
+			pos := state.Pos()
+			keys, v := keyval(state)
+			current.set(state, pos, keys, v)
+		}
+		ws.Exec(state)
+		if r, ok := peek(state); ok && r == '#' {
+			comment.Exec(state)
+		}
+		if _, ok := peek(state); !ok {
+			break
+		}
+		if _, err := newline.Parse(state); err != nil {
+			r, _ := peek(state)
+			panic(state.Trap("Expect newline but %q", r))
+		}
+	}
+	return root.toMap()
+}
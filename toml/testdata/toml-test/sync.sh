@@ -0,0 +1,22 @@
+#!/bin/sh
+# 从上游复制 TOML 1.0 的全部用例，并把所用的提交记录在 UPSTREAM 文件中。
+# 用法： sh sync.sh <toml-test 的提交或者标签>
+set -e
+ref=${1:?usage: sh sync.sh <upstream commit>}
+dir=$(cd "$(dirname "$0")" && pwd)
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+git clone -q https://github.com/toml-lang/toml-test.git "$tmp"
+git -C "$tmp" checkout -q "$ref"
+rm -rf "$dir/valid" "$dir/invalid"
+cd "$tmp/tests"
+if [ -f files-toml-1.0.0 ]; then
+	# 较新的版本同时收录了 TOML 1.1 的用例，只复制 1.0 的清单中的文件
+	grep -E '^(valid|invalid)/' files-toml-1.0.0 | while read -r f; do
+		mkdir -p "$dir/$(dirname "$f")"
+		cp "$f" "$dir/$f"
+	done
+else
+	cp -R valid invalid "$dir"
+fi
+git -C "$tmp" rev-parse HEAD > "$dir/UPSTREAM"
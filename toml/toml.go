@@ -0,0 +1,94 @@
+// Package toml 是基于 goP2 的 TOML 1.0 解析器。
+//
+// 解析结果为嵌套的 map[string]interface{} ，值的类型为：字符串 string ，整数 int64 ，
+// 浮点数 float64 ，布尔值 bool ，带时区偏移的日期时间 time.Time ，本地日期时间 LocalDateTime ，
+// 本地日期 LocalDate ，本地时间 LocalTime ，数组 []interface{} ，内联表和普通表
+// map[string]interface{} ，表数组 []map[string]interface{} 。
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// LocalDate 是不带时区的日期
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func (d LocalDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// LocalTime 是不带时区的时刻，超过纳秒的精度会被截断
+type LocalTime struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+func (t LocalTime) String() string {
+	re := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		re += strings.TrimRight(fmt.Sprintf(".%09d", t.Nanosecond), "0")
+	}
+	return re
+}
+
+// LocalDateTime 是不带时区的日期时间
+type LocalDateTime struct {
+	Date LocalDate
+	Time LocalTime
+}
+
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + "T" + dt.Time.String()
+}
+
+// In 返回 dt 在 loc 时区中对应的 time.Time
+func (dt LocalDateTime) In(loc *time.Location) time.Time {
+	return time.Date(dt.Date.Year, dt.Date.Month, dt.Date.Day,
+		dt.Time.Hour, dt.Time.Minute, dt.Time.Second, dt.Time.Nanosecond, loc)
+}
+
+// ParseError 是带有行列位置的解析错误，行列都从 1 开始
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("toml: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func parseError(data []rune, err error) *ParseError {
+	e, ok := err.(goP2.Error)
+	if !ok {
+		return &ParseError{1, 1, err.Error()}
+	}
+	at := goP2.LineCol(data, e.Pos)
+	return &ParseError{at.Line, at.Column, e.Message}
+}
+
+// Value 解析一个 TOML 值，包括字符串、数字、布尔值、日期时间、数组和内联表
+var Value = goP2.Do(value)
+
+// Document 解析完整的 TOML 文档，返回 map[string]interface{}
+var Document = goP2.Do(document)
+
+// Parse 解析 TOML 文本，错误总是 *ParseError
+func Parse(text string) (map[string]interface{}, error) {
+	state := goP2.BasicStateFromText(text)
+	re, err := Document.Parse(&state)
+	if err != nil {
+		return nil, parseError([]rune(text), err)
+	}
+	return re.(map[string]interface{}), nil
+}
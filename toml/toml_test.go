@@ -0,0 +1,206 @@
+package toml
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtures = "testdata/toml-test"
+
+// tagged 将解析结果转换为 toml-test 使用的带类型标注的 JSON 结构
+func tagged(x interface{}) interface{} {
+	tag := func(typ, value string) interface{} {
+		return map[string]interface{}{"type": typ, "value": value}
+	}
+	switch v := x.(type) {
+	case map[string]interface{}:
+		re := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			re[key] = tagged(item)
+		}
+		return re
+	case []map[string]interface{}:
+		re := make([]interface{}, len(v))
+		for i, item := range v {
+			re[i] = tagged(item)
+		}
+		return re
+	case []interface{}:
+		re := make([]interface{}, len(v))
+		for i, item := range v {
+			re[i] = tagged(item)
+		}
+		return re
+	case string:
+		return tag("string", v)
+	case int64:
+		return tag("integer", strconv.FormatInt(v, 10))
+	case float64:
+		return tag("float", strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		return tag("bool", strconv.FormatBool(v))
+	case time.Time:
+		return tag("datetime", v.Format(time.RFC3339Nano))
+	case LocalDateTime:
+		return tag("datetime-local", v.String())
+	case LocalDate:
+		return tag("date-local", v.String())
+	case LocalTime:
+		return tag("time-local", v.String())
+	}
+	panic("unexpected value type " + reflect.TypeOf(x).String())
+}
+
+// equal 比较两个带类型标注的结构，浮点数按数值比较，带时区的日期时间按时刻比较
+func equal(got, expect interface{}) bool {
+	switch e := expect.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(g) != len(e) {
+			return false
+		}
+		if typ, ok := e["type"].(string); ok && len(e) == 2 {
+			if _, ok := e["value"].(string); ok {
+				return equalLeaf(typ, g, e)
+			}
+		}
+		for key, item := range e {
+			if !equal(g[key], item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !equal(g[i], e[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func equalLeaf(typ string, got, expect map[string]interface{}) bool {
+	if got["type"] != typ {
+		return false
+	}
+	g, e := got["value"].(string), expect["value"].(string)
+	switch typ {
+	case "float":
+		gf, err1 := strconv.ParseFloat(g, 64)
+		ef, err2 := strconv.ParseFloat(e, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return gf == ef || math.IsNaN(gf) && math.IsNaN(ef)
+	case "datetime":
+		gt, err1 := time.Parse(time.RFC3339Nano, g)
+		et, err2 := time.Parse(time.RFC3339Nano, e)
+		return err1 == nil && err2 == nil && gt.Equal(et)
+	}
+	return g == e
+}
+
+func walkFixtures(t *testing.T, dir string, fn func(name string, src string)) {
+	err := filepath.Walk(filepath.Join(fixtures, dir), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".toml") {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fn(path, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidFixtures(t *testing.T) {
+	walkFixtures(t, "valid", func(name, src string) {
+		re, err := Parse(src)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", name, err)
+			return
+		}
+		// 每个合法的用例都必须有期望的结果
+		data, err := ioutil.ReadFile(strings.TrimSuffix(name, ".toml") + ".json")
+		if err != nil {
+			t.Fatalf("%s: missing the expected JSON: %v", name, err)
+		}
+		var expect interface{}
+		if err := json.Unmarshal(data, &expect); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := tagged(re); !equal(got, expect) {
+			t.Errorf("%s: Expect %v but %v", name, expect, got)
+		}
+	})
+}
+
+func TestInvalidFixtures(t *testing.T) {
+	walkFixtures(t, "invalid", func(name, src string) {
+		if re, err := Parse(src); err == nil {
+			t.Errorf("%s: Expect error but %v", name, re)
+		}
+	})
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("[server]\nhost = \"a\"\nport = 80\nport = 81\n")
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Expect *ParseError but %#v", err)
+	}
+	if e.Line != 4 || e.Column != 1 || !strings.Contains(e.Message, "duplicate key port") {
+		t.Fatalf("Expect duplicate key at line 4, column 1 but %v", e)
+	}
+	_, err = Parse("a = [1, 2\nb = 3\n")
+	e, ok = err.(*ParseError)
+	if !ok || e.Line != 2 || e.Column != 1 {
+		t.Fatalf("Expect error at line 2, column 1 but %v", err)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	re, err := Parse(`
+n = 1
+x = 1.5
+when = 1979-05-27T07:32:00-08:00
+day = 1979-05-27
+[[item]]
+name = "a"
+`)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if re["n"] != int64(1) || re["x"] != 1.5 {
+		t.Fatalf("Expect int64 and float64 but %#v and %#v", re["n"], re["x"])
+	}
+	when, ok := re["when"].(time.Time)
+	if !ok || !when.Equal(time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC)) {
+		t.Fatalf("Expect time.Time but %#v", re["when"])
+	}
+	if re["day"] != (LocalDate{1979, time.May, 27}) {
+		t.Fatalf("Expect LocalDate but %#v", re["day"])
+	}
+	items, ok := re["item"].([]map[string]interface{})
+	if !ok || len(items) != 1 || items[0]["name"] != "a" {
+		t.Fatalf("Expect array of tables but %#v", re["item"])
+	}
+}
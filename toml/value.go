@@ -0,0 +1,529 @@
+package toml
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+// ws 跳过空格和制表符
+var ws = goP2.Skip(goP2.RuneOf(" \t"))
+
+var newline = goP2.Choice(goP2.Try(goP2.Chr('\n')), goP2.Try(goP2.Str("\r\n")))
+
+// isControl 判断 r 是否为 TOML 中不允许直接出现的控制字符，制表符除外
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t') || r == 0x7f
+}
+
+// peek 返回下一个字符但是不消费它，到达结尾时返回 false
+func peek(state goP2.State) (rune, bool) {
+	pos := state.Pos()
+	x, err := state.Next()
+	if err != nil {
+		return 0, false
+	}
+	state.SeekTo(pos)
+	r, ok := x.(rune)
+	return r, ok
+}
+
+// accept 在下一个字符属于 set 时消费并返回它，否则不消费输入
+func accept(state goP2.State, set string) (rune, bool) {
+	x, err := goP2.Try(goP2.RuneOf(set)).Parse(state)
+	if err != nil {
+		return 0, false
+	}
+	return x.(rune), true
+}
+
+// expect 消费字符 r ，否则在当前位置报错
+func expect(state goP2.State, r rune, message string) {
+	if _, ok := accept(state, string([]rune{r})); !ok {
+		panic(state.Trap("%s", message))
+	}
+}
+
+// next 消费并返回下一个字符，到达结尾时以 message 报错
+func next(state goP2.State, message string) rune {
+	x, err := state.Next()
+	if err != nil {
+		panic(state.Trap("%s", message))
+	}
+	return x.(rune)
+}
+
+var comment = goP2.Do(func(state goP2.State) interface{} {
+	goP2.Chr('#').Exec(state)
+	for {
+		r, ok := peek(state)
+		if !ok || r == '\n' || r == '\r' {
+			return nil
+		}
+		if isControl(r) {
+			panic(state.Trap("control character %U in comment", r))
+		}
+		state.Next()
+	}
+})
+
+var bareKey = goP2.Many1(goP2.RuneP("bare key", func(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' || r == '-'
+})).Bind(goP2.ReturnString)
+
+func simpleKey(state goP2.State) string {
+	if _, ok := accept(state, "\""); ok {
+		return basicString(state)
+	}
+	if _, ok := accept(state, "'"); ok {
+		return literalString(state)
+	}
+	pos := state.Pos()
+	k, err := bareKey.Parse(state)
+	if err != nil {
+		state.SeekTo(pos)
+		r, _ := peek(state)
+		panic(state.Trap("Expect a key but %q", r))
+	}
+	return k.(string)
+}
+
+// key 解析可能带点的键，点号两侧允许空白
+func key(state goP2.State) []string {
+	keys := []string{simpleKey(state)}
+	for {
+		if _, err := goP2.Try(ws.Then(goP2.Chr('.'))).Parse(state); err != nil {
+			return keys
+		}
+		ws.Exec(state)
+		keys = append(keys, simpleKey(state))
+	}
+}
+
+func value(state goP2.State) interface{} {
+	r, ok := peek(state)
+	if !ok {
+		panic(state.Trap("Expect a value but end of input"))
+	}
+	switch {
+	case r == '"':
+		if _, err := goP2.Try(goP2.Str(`"""`)).Parse(state); err == nil {
+			return multilineBasicString(state)
+		}
+		state.Next()
+		return basicString(state)
+	case r == '\'':
+		if _, err := goP2.Try(goP2.Str("'''")).Parse(state); err == nil {
+			return multilineLiteralString(state)
+		}
+		state.Next()
+		return literalString(state)
+	case r == 't':
+		return goP2.Str("true").Then(goP2.Return(true)).Exec(state)
+	case r == 'f':
+		return goP2.Str("false").Then(goP2.Return(false)).Exec(state)
+	case r == '[':
+		return array(state)
+	case r == '{':
+		return inlineTable(state)
+	case '0' <= r && r <= '9':
+		if re, ok := datetime(state); ok {
+			return re
+		}
+		return number(state)
+	case r == '+' || r == '-' || r == 'i' || r == 'n':
+		return number(state)
+	}
+	panic(state.Trap("Expect a value but %q", r))
+}
+
+// skipArraySpace 跳过数组中的空白、换行和注释
+func skipArraySpace(state goP2.State) {
+	for {
+		ws.Exec(state)
+		if r, ok := peek(state); ok && r == '#' {
+			comment.Exec(state)
+		}
+		if _, err := newline.Parse(state); err != nil {
+			return
+		}
+	}
+}
+
+func array(state goP2.State) interface{} {
+	goP2.Chr('[').Exec(state)
+	re := []interface{}{}
+	for {
+		skipArraySpace(state)
+		if _, ok := accept(state, "]"); ok {
+			return re
+		}
+		re = append(re, value(state))
+		skipArraySpace(state)
+		if _, ok := accept(state, "]"); ok {
+			return re
+		}
+		expect(state, ',', "Expect ',' or ']' in array")
+	}
+}
+
+// inlineTable 解析 { key = value, ... } ，内联表必须写在一行之内并且不允许尾随逗号
+func inlineTable(state goP2.State) interface{} {
+	goP2.Chr('{').Exec(state)
+	table := newTable(explicitTable)
+	ws.Exec(state)
+	if _, ok := accept(state, "}"); ok {
+		return table.toMap()
+	}
+	for {
+		ws.Exec(state)
+		pos := state.Pos()
+		keys, v := keyval(state)
+		table.set(state, pos, keys, v)
+		ws.Exec(state)
+		if _, ok := accept(state, "}"); ok {
+			return table.toMap()
+		}
+		expect(state, ',', "Expect ',' or '}' in inline table")
+	}
+}
+
+// basicString 解析起始引号之后的基本字符串
+func basicString(state goP2.State) string {
+	var b strings.Builder
+	for {
+		pos := state.Pos()
+		r := next(state, "unterminated string")
+		switch {
+		case r == '"':
+			return b.String()
+		case r == '\\':
+			escape(state, &b)
+		case isControl(r):
+			failAt(state, pos, "control character %U in string", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// quotes 在遇到一个引号 q 后统计连续的引号，三个引号结束多行字符串，
+// 结尾处允许再多出两个作为内容的引号
+func quotes(state goP2.State, q rune, b *strings.Builder) bool {
+	n := 1
+	for {
+		if _, ok := accept(state, string([]rune{q})); !ok {
+			break
+		}
+		n++
+	}
+	if n > 5 {
+		panic(state.Trap("too many quotes at the end of multi-line string"))
+	}
+	closed := n >= 3
+	if closed {
+		n -= 3
+	}
+	for i := 0; i < n; i++ {
+		b.WriteRune(q)
+	}
+	return closed
+}
+
+// lineEnd 消费 \n 或者 \r\n ，并把它写入 b
+func lineEnd(state goP2.State, r rune, b *strings.Builder) {
+	if r == '\r' {
+		if _, ok := accept(state, "\n"); !ok {
+			panic(state.Trap("bare carriage return in string"))
+		}
+		b.WriteString("\r\n")
+		return
+	}
+	b.WriteRune(r)
+}
+
+func multilineBasicString(state goP2.State) string {
+	newline.Parse(state)
+	var b strings.Builder
+	for {
+		pos := state.Pos()
+		r := next(state, "unterminated multi-line string")
+		switch {
+		case r == '"':
+			if quotes(state, r, &b) {
+				return b.String()
+			}
+		case r == '\\':
+			if _, err := goP2.Try(ws.Then(newline)).Parse(state); err == nil {
+				goP2.Skip(goP2.Choice(goP2.Try(goP2.RuneOf(" \t")), newline)).Exec(state)
+				continue
+			}
+			escape(state, &b)
+		case r == '\n' || r == '\r':
+			lineEnd(state, r, &b)
+		case isControl(r):
+			failAt(state, pos, "control character %U in string", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// literalString 解析起始单引号之后的字面量字符串
+func literalString(state goP2.State) string {
+	var b strings.Builder
+	for {
+		pos := state.Pos()
+		r := next(state, "unterminated string")
+		switch {
+		case r == '\'':
+			return b.String()
+		case isControl(r):
+			failAt(state, pos, "control character %U in string", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func multilineLiteralString(state goP2.State) string {
+	newline.Parse(state)
+	var b strings.Builder
+	for {
+		pos := state.Pos()
+		r := next(state, "unterminated multi-line string")
+		switch {
+		case r == '\'':
+			if quotes(state, r, &b) {
+				return b.String()
+			}
+		case r == '\n' || r == '\r':
+			lineEnd(state, r, &b)
+		case isControl(r):
+			failAt(state, pos, "control character %U in string", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+var simpleEscapes = map[rune]rune{
+	'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\',
+}
+
+func escape(state goP2.State, b *strings.Builder) {
+	r := next(state, "unterminated escape")
+	if c, ok := simpleEscapes[r]; ok {
+		b.WriteRune(c)
+		return
+	}
+	n := 0
+	switch r {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		panic(state.Trap("invalid escape '\\%s'", string([]rune{r})))
+	}
+	text := goP2.ToString(goP2.Times(n, goP2.RuneOf("0123456789abcdefABCDEF")).Exec(state))
+	v, _ := strconv.ParseUint(text, 16, 32)
+	if !utf8.ValidRune(rune(v)) {
+		panic(state.Trap("invalid unicode scalar value \\%s%s", string([]rune{r}), text))
+	}
+	b.WriteRune(rune(v))
+}
+
+const decimal = "0123456789"
+
+// digits 解析由 set 中的数字组成、数字之间可以用单个下划线分隔的序列，返回去掉下划线的文本
+func digits(state goP2.State, set string) string {
+	var b strings.Builder
+	first, ok := accept(state, set)
+	if !ok {
+		r, _ := peek(state)
+		panic(state.Trap("Expect digit but %q", r))
+	}
+	b.WriteRune(first)
+	separated := goP2.Try(goP2.Chr('_').Then(goP2.RuneOf(set)))
+	for {
+		if r, ok := accept(state, set); ok {
+			b.WriteRune(r)
+			continue
+		}
+		if r, err := separated.Parse(state); err == nil {
+			b.WriteRune(r.(rune))
+			continue
+		}
+		break
+	}
+	if r, ok := peek(state); ok && r == '_' {
+		panic(state.Trap("underscore must be surrounded by digits"))
+	}
+	return b.String()
+}
+
+var prefixes = []struct {
+	prefix string
+	base   int
+	digits string
+}{
+	{"0x", 16, "0123456789abcdefABCDEF"},
+	{"0o", 8, "01234567"},
+	{"0b", 2, "01"},
+}
+
+func number(state goP2.State) interface{} {
+	start := state.Pos()
+	sign, signed := accept(state, "+-")
+	if _, err := goP2.Try(goP2.Str("inf")).Parse(state); err == nil {
+		if sign == '-' {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+	if _, err := goP2.Try(goP2.Str("nan")).Parse(state); err == nil {
+		return math.NaN()
+	}
+	if !signed {
+		for _, p := range prefixes {
+			if _, err := goP2.Try(goP2.Str(p.prefix)).Parse(state); err == nil {
+				v, err := strconv.ParseInt(digits(state, p.digits), p.base, 64)
+				if err != nil {
+					failAt(state, start, "integer out of range")
+				}
+				return v
+			}
+		}
+	}
+	text := ""
+	if sign == '-' {
+		text = "-"
+	}
+	integer := digits(state, decimal)
+	if len(integer) > 1 && integer[0] == '0' {
+		failAt(state, start, "leading zeros are not allowed")
+	}
+	text += integer
+	float := false
+	if _, ok := accept(state, "."); ok {
+		text += "." + digits(state, decimal)
+		float = true
+	}
+	if _, ok := accept(state, "eE"); ok {
+		text += "e"
+		if s, ok := accept(state, "+-"); ok {
+			text += string([]rune{s})
+		}
+		text += digits(state, decimal)
+		float = true
+	}
+	if float {
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			failAt(state, start, "float out of range")
+		}
+		return v
+	}
+	v, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		failAt(state, start, "integer out of range")
+	}
+	return v
+}
+
+// fixed 解析 n 位十进制数字
+func fixed(state goP2.State, n int) int {
+	v, _ := strconv.Atoi(goP2.ToString(goP2.Times(n, goP2.RuneOf(decimal)).Exec(state)))
+	return v
+}
+
+var datePart = goP2.Do(func(state goP2.State) interface{} {
+	year := fixed(state, 4)
+	goP2.Chr('-').Exec(state)
+	month := fixed(state, 2)
+	goP2.Chr('-').Exec(state)
+	return LocalDate{year, time.Month(month), fixed(state, 2)}
+})
+
+var timePart = goP2.Do(func(state goP2.State) interface{} {
+	hour := fixed(state, 2)
+	goP2.Chr(':').Exec(state)
+	minute := fixed(state, 2)
+	goP2.Chr(':').Exec(state)
+	re := LocalTime{hour, minute, fixed(state, 2), 0}
+	if _, ok := accept(state, "."); ok {
+		fraction := goP2.ToString(goP2.Many1(goP2.RuneOf(decimal)).Exec(state))
+		fraction = (fraction + "000000000")[:9]
+		re.Nanosecond, _ = strconv.Atoi(fraction)
+	}
+	return re
+})
+
+var offsetPart = goP2.Do(func(state goP2.State) interface{} {
+	sign := goP2.RuneOf("+-").Exec(state).(rune)
+	hour := fixed(state, 2)
+	goP2.Chr(':').Exec(state)
+	minute := fixed(state, 2)
+	if hour > 23 || minute > 59 {
+		panic(state.Trap("invalid time offset"))
+	}
+	offset := hour*3600 + minute*60
+	if sign == '-' {
+		offset = -offset
+	}
+	return offset
+})
+
+func validDate(d LocalDate) bool {
+	if d.Month < 1 || d.Month > 12 || d.Day < 1 {
+		return false
+	}
+	// 下个月的第 0 天即为本月的最后一天
+	last := time.Date(d.Year, d.Month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return d.Day <= last
+}
+
+func validTime(t LocalTime) bool {
+	return t.Hour < 24 && t.Minute < 60 && t.Second < 60
+}
+
+// datetime 尝试解析日期时间，如果后续输入不是日期或者时间的形式，返回 false 并且不消费输入
+func datetime(state goP2.State) (interface{}, bool) {
+	start := state.Pos()
+	d, err := goP2.Try(datePart).Parse(state)
+	if err != nil {
+		t, err := goP2.Try(timePart).Parse(state)
+		if err != nil {
+			return nil, false
+		}
+		if !validTime(t.(LocalTime)) {
+			failAt(state, start, "invalid time %v", t)
+		}
+		return t, true
+	}
+	date := d.(LocalDate)
+	if !validDate(date) {
+		failAt(state, start, "invalid date %v", date)
+	}
+	t, err := goP2.Try(goP2.RuneOf("Tt ").Then(timePart)).Parse(state)
+	if err != nil {
+		return date, true
+	}
+	clock := t.(LocalTime)
+	if !validTime(clock) {
+		failAt(state, start, "invalid time %v", clock)
+	}
+	dt := LocalDateTime{date, clock}
+	if _, ok := accept(state, "Zz"); ok {
+		return dt.In(time.UTC), true
+	}
+	if offset, err := goP2.Try(offsetPart).Parse(state); err == nil {
+		return dt.In(time.FixedZone("", offset.(int))), true
+	}
+	return dt, true
+}
@@ -0,0 +1,484 @@
+// Package uri 按照 RFC 3986 的 ABNF 解析 URI 和 URI 引用，提供 scheme 、 authority 、
+// userinfo 、 host （包括 IP-literal）、 port 、各种 path 、 query 和 fragment 的解析算子。
+//
+// 与 net/url 不同，这里的算子严格遵循 RFC 3986 ，并且只消费 URI 本身，可以嵌入到更大的文法中。
+// 解析的结果记录了每个组成部分的原文、百分号解码之后的值以及源码范围。
+package uri
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+	"github.com/Dwarfartisan/goparsec2/netaddr"
+)
+
+const (
+	alpha      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digit      = "0123456789"
+	hex        = "0123456789abcdefABCDEF"
+	unreserved = alpha + digit + "-._~"
+	subDelims  = "!$&'()*+,;="
+)
+
+// Span 是组成部分在源文本中的范围，以 rune 计的偏移， End 不包含在内
+type Span struct {
+	Start int
+	End   int
+}
+
+// Component 是 URI 的一个组成部分
+type Component struct {
+	// Raw 是组成部分的原文
+	Raw string
+	// Value 是百分号解码之后的值。解码之后的 path 无法区分 / 和 %2F ，需要按段处理时请使用 Raw
+	Value string
+	Span  Span
+	// Defined 表示该部分是否出现，用于区分空的 query "?" 和没有 query
+	Defined bool
+}
+
+// Authority 是 [ userinfo "@" ] host [ ":" port ]
+type Authority struct {
+	Userinfo Component
+	// Host 的 Raw 对于 IP-literal 包括方括号， Value 是去掉方括号并且解码 zone 之后的地址
+	Host Component
+	// Addr 在 host 是 IPv4 地址或者 IPv6 的 IP-literal 时有效
+	Addr netip.Addr
+	Port Component
+	Span Span
+}
+
+func (a *Authority) String() string {
+	var b strings.Builder
+	if a.Userinfo.Defined {
+		b.WriteString(a.Userinfo.Raw)
+		b.WriteByte('@')
+	}
+	b.WriteString(a.Host.Raw)
+	if a.Port.Defined {
+		b.WriteByte(':')
+		b.WriteString(a.Port.Raw)
+	}
+	return b.String()
+}
+
+// URI 是 URI 或者相对引用的解析结果，没有 authority 时 Authority 为 nil
+type URI struct {
+	Scheme    Component
+	Authority *Authority
+	Path      Component
+	Query     Component
+	Fragment  Component
+	Span      Span
+}
+
+// String 按照 RFC 3986 5.3 节用各部分的原文重新组合 URI
+func (u *URI) String() string {
+	var b strings.Builder
+	if u.Scheme.Defined {
+		b.WriteString(u.Scheme.Raw)
+		b.WriteByte(':')
+	}
+	if u.Authority != nil {
+		b.WriteString("//")
+		b.WriteString(u.Authority.String())
+	}
+	b.WriteString(u.Path.Raw)
+	if u.Query.Defined {
+		b.WriteByte('?')
+		b.WriteString(u.Query.Raw)
+	}
+	if u.Fragment.Defined {
+		b.WriteByte('#')
+		b.WriteString(u.Fragment.Raw)
+	}
+	return b.String()
+}
+
+// IsAbsolute 判断 URI 是否带有 scheme
+func (u *URI) IsAbsolute() bool {
+	return u.Scheme.Defined
+}
+
+// ParseError 是 URI 解析错误， Offset 是以 rune 计的偏移
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("uri: offset %d: %s", e.Offset, e.Message)
+}
+
+// fail 将 state 移回 pos 并在该位置报错
+func fail(state goP2.State, pos int, message string, args ...interface{}) {
+	state.SeekTo(pos)
+	panic(state.Trap(message, args...))
+}
+
+// peek 返回下一个字符但是不消费它，到达结尾时返回 false
+func peek(state goP2.State) (rune, bool) {
+	pos := state.Pos()
+	x, err := state.Next()
+	if err != nil {
+		return 0, false
+	}
+	state.SeekTo(pos)
+	r, ok := x.(rune)
+	return r, ok
+}
+
+func unhex(r rune) byte {
+	switch {
+	case '0' <= r && r <= '9':
+		return byte(r - '0')
+	case 'a' <= r && r <= 'f':
+		return byte(r - 'a' + 10)
+	}
+	return byte(r - 'A' + 10)
+}
+
+// chars 解析由 unreserved 、 pct-encoded 以及 set 中的字符组成的序列，返回原文和解码之后的值
+func chars(state goP2.State, set string) (string, string) {
+	var raw strings.Builder
+	var value []byte
+	for {
+		r, ok := peek(state)
+		if !ok {
+			break
+		}
+		if r == '%' {
+			pos := state.Pos()
+			state.Next()
+			x, err := goP2.Times(2, goP2.RuneOf(hex)).Parse(state)
+			if err != nil {
+				fail(state, pos, "invalid percent-encoding")
+			}
+			h := x.([]interface{})
+			raw.WriteRune(r)
+			raw.WriteRune(h[0].(rune))
+			raw.WriteRune(h[1].(rune))
+			value = append(value, unhex(h[0].(rune))<<4|unhex(h[1].(rune)))
+			continue
+		}
+		if !strings.ContainsRune(unreserved, r) && !strings.ContainsRune(set, r) {
+			break
+		}
+		state.Next()
+		raw.WriteRune(r)
+		value = append(value, byte(r))
+	}
+	return raw.String(), string(value)
+}
+
+// component 解析 chars 并记录范围
+func component(state goP2.State, set string) Component {
+	start := state.Pos()
+	raw, value := chars(state, set)
+	return Component{raw, value, Span{start, state.Pos()}, true}
+}
+
+// PercentDecode 对 s 做百分号解码， % 之后不是两个十六进制数字时返回错误
+func PercentDecode(s string) (string, error) {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b = append(b, s[i])
+			continue
+		}
+		if i+2 >= len(s) || !strings.ContainsRune(hex, rune(s[i+1])) || !strings.ContainsRune(hex, rune(s[i+2])) {
+			return "", &ParseError{i, "invalid percent-encoding"}
+		}
+		b = append(b, unhex(rune(s[i+1]))<<4|unhex(rune(s[i+2])))
+		i += 2
+	}
+	return string(b), nil
+}
+
+// Scheme 解析 ALPHA *( ALPHA / DIGIT / "+" / "-" / "." ) ，结果为 Component
+var Scheme = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	if r, ok := peek(state); !ok || !strings.ContainsRune(alpha, r) {
+		fail(state, start, "scheme must begin with a letter")
+	}
+	text := goP2.ToString(goP2.Many1(goP2.RuneOf(alpha + digit + "+-.")).Exec(state))
+	return Component{text, text, Span{start, state.Pos()}, true}
+})
+
+// Userinfo 解析 *( unreserved / pct-encoded / sub-delims / ":" ) ，结果为 Component ，
+// 之后的 "@" 由 Authority 处理
+var Userinfo = goP2.Do(func(state goP2.State) interface{} {
+	return component(state, subDelims+":")
+})
+
+// ipLiteral 解析 "[" ( IPv6address / IPvFuture ) "]" ， IPv6 地址可以带有 RFC 6874 的 %25zone
+func ipLiteral(state goP2.State) (Component, netip.Addr) {
+	start := state.Pos()
+	goP2.Chr('[').Exec(state)
+	var addr netip.Addr
+	var raw, value string
+	if v, err := goP2.Try(goP2.RuneOf("vV")).Parse(state); err == nil {
+		version := goP2.ToString(goP2.Many1(goP2.RuneOf(hex)).Exec(state))
+		goP2.Chr('.').Exec(state)
+		text := goP2.ToString(goP2.Many1(goP2.RuneOf(unreserved + subDelims + ":")).Exec(state))
+		raw = string(v.(rune)) + version + "." + text
+		value = raw
+	} else {
+		pos := state.Pos()
+		text := goP2.ToString(goP2.Many(goP2.RuneOf(hex + ":.")).Exec(state))
+		sub := goP2.BasicStateFromText(text)
+		x, err := netaddr.IPv6.Over(goP2.EOF).Parse(&sub)
+		if err != nil {
+			fail(state, pos, "invalid IPv6 address %s", text)
+		}
+		addr = x.(netip.Addr)
+		raw, value = text, text
+		if _, err := goP2.Try(goP2.Str("%25")).Parse(state); err == nil {
+			zonePos := state.Pos()
+			r, zone := chars(state, "")
+			if zone == "" {
+				fail(state, zonePos, "empty IPv6 zone")
+			}
+			addr = addr.WithZone(zone)
+			raw += "%25" + r
+			value += "%" + zone
+		}
+	}
+	if _, err := goP2.Chr(']').Parse(state); err != nil {
+		fail(state, start, "unclosed IP literal")
+	}
+	return Component{"[" + raw + "]", value, Span{start, state.Pos()}, true}, addr
+}
+
+// Host 解析 IP-literal 、 IPv4address 或者 reg-name ，结果为 Component
+var Host = goP2.Do(func(state goP2.State) interface{} {
+	c, _ := host(state)
+	return c
+})
+
+func host(state goP2.State) (Component, netip.Addr) {
+	start := state.Pos()
+	if r, ok := peek(state); ok && r == '[' {
+		return ipLiteral(state)
+	}
+	// IPv4address 之后如果还有 reg-name 的字符，整个 host 就是 reg-name ，例如 1.2.3.456
+	if x, err := goP2.Try(netaddr.IPv4).Parse(state); err == nil {
+		if r, ok := peek(state); !ok || r != '%' && !strings.ContainsRune(unreserved+subDelims, r) {
+			text := x.(netip.Addr).String()
+			return Component{text, text, Span{start, state.Pos()}, true}, x.(netip.Addr)
+		}
+		state.SeekTo(start)
+	}
+	return component(state, subDelims), netip.Addr{}
+}
+
+// Port 解析 *DIGIT ，结果为 Component
+var Port = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	text := goP2.ToString(goP2.Many(goP2.RuneOf(digit)).Exec(state))
+	return Component{text, text, Span{start, state.Pos()}, true}
+})
+
+// AuthorityP 解析 [ userinfo "@" ] host [ ":" port ] ，结果为 *Authority
+var AuthorityP = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	re := &Authority{}
+	if x, err := goP2.Try(Userinfo.Over(goP2.Chr('@'))).Parse(state); err == nil {
+		re.Userinfo = x.(Component)
+	}
+	re.Host, re.Addr = host(state)
+	if _, err := goP2.Try(goP2.Chr(':')).Parse(state); err == nil {
+		re.Port = Port.Exec(state).(Component)
+	}
+	re.Span = Span{start, state.Pos()}
+	return re
+})
+
+// segments 解析 *( "/" segment ) 并追加到 raw 和 value
+func segments(state goP2.State, raw, value *strings.Builder) {
+	for {
+		if _, err := goP2.Try(goP2.Chr('/')).Parse(state); err != nil {
+			return
+		}
+		r, v := segment(state)
+		raw.WriteString("/" + r)
+		value.WriteString("/" + v)
+	}
+}
+
+// path 解析以 first 为第一段的路径， first 为 nil 时没有第一段
+func path(state goP2.State, first func(goP2.State) (string, string)) Component {
+	start := state.Pos()
+	var raw, value strings.Builder
+	if first != nil {
+		r, v := first(state)
+		raw.WriteString(r)
+		value.WriteString(v)
+	}
+	segments(state, &raw, &value)
+	return Component{raw.String(), value.String(), Span{start, state.Pos()}, true}
+}
+
+// segmentNZ 解析非空的段， set 是 unreserved 和 pct-encoded 之外允许的字符
+func segmentNZ(set string) func(goP2.State) (string, string) {
+	return func(state goP2.State) (string, string) {
+		pos := state.Pos()
+		r, v := chars(state, set)
+		if r == "" {
+			fail(state, pos, "Expect a non-empty path segment")
+		}
+		return r, v
+	}
+}
+
+// PathAbEmpty 解析 *( "/" segment ) ，结果为 Component
+var PathAbEmpty = goP2.Do(func(state goP2.State) interface{} {
+	return path(state, nil)
+})
+
+// PathAbsolute 解析 "/" [ segment-nz *( "/" segment ) ] ，结果为 Component
+var PathAbsolute = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	goP2.Chr('/').Exec(state)
+	if r, ok := peek(state); ok && r == '/' {
+		fail(state, start, "path-absolute cannot begin with //")
+	}
+	rest := path(state, segment)
+	return Component{"/" + rest.Raw, "/" + rest.Value, Span{start, state.Pos()}, true}
+})
+
+// segment 解析 *pchar
+func segment(state goP2.State) (string, string) {
+	return chars(state, subDelims+":@")
+}
+
+// PathNoScheme 解析 segment-nz-nc *( "/" segment ) ，第一段中不能有冒号，结果为 Component
+var PathNoScheme = goP2.Do(func(state goP2.State) interface{} {
+	return path(state, segmentNZ(subDelims+"@"))
+})
+
+// PathRootless 解析 segment-nz *( "/" segment ) ，结果为 Component
+var PathRootless = goP2.Do(func(state goP2.State) interface{} {
+	return path(state, segmentNZ(subDelims+":@"))
+})
+
+// Query 解析 *( pchar / "/" / "?" ) ，结果为 Component ，之前的 "?" 由外层处理
+var Query = goP2.Do(func(state goP2.State) interface{} {
+	return component(state, subDelims+":@/?")
+})
+
+// Fragment 解析 *( pchar / "/" / "?" ) ，结果为 Component ，之前的 "#" 由外层处理
+var Fragment = goP2.Do(func(state goP2.State) interface{} {
+	return component(state, subDelims+":@/?")
+})
+
+// startsPath 判断下一个字符能否开始一个非空的段
+func startsPath(state goP2.State, set string) bool {
+	r, ok := peek(state)
+	return ok && (r == '%' || strings.ContainsRune(unreserved+set, r))
+}
+
+// hierPart 解析 "//" authority path-abempty / path-absolute / rootless / path-empty ，
+// rootless 是 path-rootless 或者 path-noscheme
+func hierPart(state goP2.State, re *URI, rootless goP2.P, set string) {
+	if _, err := goP2.Try(goP2.Str("//")).Parse(state); err == nil {
+		re.Authority = AuthorityP.Exec(state).(*Authority)
+		re.Path = PathAbEmpty.Exec(state).(Component)
+		return
+	}
+	if r, ok := peek(state); ok && r == '/' {
+		re.Path = PathAbsolute.Exec(state).(Component)
+		return
+	}
+	if startsPath(state, set) {
+		re.Path = rootless.Exec(state).(Component)
+		return
+	}
+	pos := state.Pos()
+	re.Path = Component{Span: Span{pos, pos}, Defined: true}
+}
+
+func tail(state goP2.State, re *URI, fragment bool) {
+	if _, err := goP2.Try(goP2.Chr('?')).Parse(state); err == nil {
+		re.Query = Query.Exec(state).(Component)
+	}
+	if !fragment {
+		return
+	}
+	if _, err := goP2.Try(goP2.Chr('#')).Parse(state); err == nil {
+		re.Fragment = Fragment.Exec(state).(Component)
+	}
+}
+
+func absolute(fragment bool) goP2.P {
+	return goP2.Do(func(state goP2.State) interface{} {
+		start := state.Pos()
+		re := &URI{}
+		re.Scheme = Scheme.Exec(state).(Component)
+		goP2.Chr(':').Exec(state)
+		hierPart(state, re, PathRootless, subDelims+":@")
+		tail(state, re, fragment)
+		re.Span = Span{start, state.Pos()}
+		return re
+	})
+}
+
+// URIP 解析 scheme ":" hier-part [ "?" query ] [ "#" fragment ] ，结果为 *URI
+var URIP = absolute(true)
+
+// AbsoluteURI 解析不带 fragment 的 absolute-URI ，结果为 *URI
+var AbsoluteURI = absolute(false)
+
+// RelativeRef 解析 relative-part [ "?" query ] [ "#" fragment ] ，结果为 *URI
+var RelativeRef = goP2.Do(func(state goP2.State) interface{} {
+	start := state.Pos()
+	re := &URI{}
+	hierPart(state, re, PathNoScheme, subDelims+"@")
+	// path-noscheme 的第一段中不能出现冒号，否则会被当作 scheme
+	if r, ok := peek(state); ok && r == ':' && re.Authority == nil && !strings.Contains(re.Path.Raw, "/") {
+		fail(state, start, "first segment of a relative path cannot contain ':'")
+	}
+	tail(state, re, true)
+	re.Span = Span{start, state.Pos()}
+	return re
+})
+
+// Reference 解析 URI-reference ，即 URI 或者相对引用，结果为 *URI
+var Reference = goP2.Do(func(state goP2.State) interface{} {
+	if x, err := goP2.Try(URIP).Parse(state); err == nil {
+		return x
+	}
+	return RelativeRef.Exec(state)
+})
+
+// end 在输入结束时成功，否则在剩余的第一个字符处报错
+var end = goP2.Do(func(state goP2.State) interface{} {
+	if r, ok := peek(state); ok {
+		panic(state.Trap("unexpected %q", r))
+	}
+	return nil
+})
+
+func parse(p goP2.P, text string) (*URI, error) {
+	state := goP2.BasicStateFromText(text)
+	re, err := p.Over(end).Parse(&state)
+	if err != nil {
+		if e, ok := err.(goP2.Error); ok {
+			return nil, &ParseError{e.Pos, e.Message}
+		}
+		return nil, &ParseError{state.Pos(), err.Error()}
+	}
+	return re.(*URI), nil
+}
+
+// Parse 解析必须带有 scheme 的 URI ，错误总是 *ParseError
+func Parse(text string) (*URI, error) {
+	return parse(URIP, text)
+}
+
+// ParseReference 解析 URI 或者相对引用，错误总是 *ParseError
+func ParseReference(text string) (*URI, error) {
+	return parse(Reference, text)
+}
@@ -0,0 +1,159 @@
+package uri
+
+import (
+	"net/netip"
+	"testing"
+
+	goP2 "github.com/Dwarfartisan/goparsec2"
+)
+
+func TestParse(t *testing.T) {
+	u, err := Parse("foo://us%40er:pw@example.com:8042/over/th%20ere?name=ferret#nose")
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	a := u.Authority
+	for _, c := range []struct {
+		name  string
+		got   Component
+		raw   string
+		value string
+		start int
+		end   int
+	}{
+		{"scheme", u.Scheme, "foo", "foo", 0, 3},
+		{"userinfo", a.Userinfo, "us%40er:pw", "us@er:pw", 6, 16},
+		{"host", a.Host, "example.com", "example.com", 17, 28},
+		{"port", a.Port, "8042", "8042", 29, 33},
+		{"path", u.Path, "/over/th%20ere", "/over/th ere", 33, 47},
+		{"query", u.Query, "name=ferret", "name=ferret", 48, 59},
+		{"fragment", u.Fragment, "nose", "nose", 60, 64},
+	} {
+		if !c.got.Defined || c.got.Raw != c.raw || c.got.Value != c.value || c.got.Span != (Span{c.start, c.end}) {
+			t.Errorf("%s: Expect %s (%s) at [%d, %d) but %+v", c.name, c.raw, c.value, c.start, c.end, c.got)
+		}
+	}
+	if a.Span != (Span{6, 33}) || u.Span != (Span{0, 64}) {
+		t.Errorf("Expect authority span {6 33} and uri span {0 64} but %v and %v", a.Span, u.Span)
+	}
+}
+
+func TestForms(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		host string
+		addr string
+		path string
+	}{
+		{"urn:example:animal:ferret:nose", "", "", "example:animal:ferret:nose"},
+		{"mailto:John.Doe@example.com", "", "", "John.Doe@example.com"},
+		{"file:///etc/hosts", "", "", "/etc/hosts"},
+		{"news:comp.infosystems.www.servers.unix", "", "", "comp.infosystems.www.servers.unix"},
+		{"ldap://[2001:db8::7]/c=GB?objectClass?one", "2001:db8::7", "2001:db8::7", "/c=GB"},
+		{"http://[fe80::1%25en0]:80/", "fe80::1%en0", "fe80::1%en0", "/"},
+		{"http://[v7.fe80::a+en1]", "v7.fe80::a+en1", "", ""},
+		{"telnet://192.0.2.16:80/", "192.0.2.16", "192.0.2.16", "/"},
+		{"http://1.2.3.456/", "1.2.3.456", "", "/"},
+		{"http://1.2.3.4.example/", "1.2.3.4.example", "", "/"},
+		{"tel:+1-816-555-1212", "", "", "+1-816-555-1212"},
+		{"x:/a", "", "", "/a"},
+		{"x:", "", "", ""},
+	} {
+		u, err := Parse(c.text)
+		if err != nil {
+			t.Errorf("%s: Expect success but %v", c.text, err)
+			continue
+		}
+		if u.String() != c.text {
+			t.Errorf("%s: Expect round trip but %s", c.text, u)
+		}
+		if u.Path.Value != c.path {
+			t.Errorf("%s: Expect path %q but %q", c.text, c.path, u.Path.Value)
+		}
+		if u.Authority == nil {
+			if c.host != "" {
+				t.Errorf("%s: Expect host %s but no authority", c.text, c.host)
+			}
+			continue
+		}
+		if u.Authority.Host.Value != c.host {
+			t.Errorf("%s: Expect host %s but %s", c.text, c.host, u.Authority.Host.Value)
+		}
+		if c.addr == "" && u.Authority.Addr.IsValid() || c.addr != "" && u.Authority.Addr != netip.MustParseAddr(c.addr) {
+			t.Errorf("%s: Expect addr %q but %v", c.text, c.addr, u.Authority.Addr)
+		}
+	}
+}
+
+func TestReference(t *testing.T) {
+	for _, text := range []string{
+		"", ".", "./g:h", "../../g", "//g", "?y", "#s", "g;x?y#s", "/g", "g/h:i", "//user@host:/p",
+	} {
+		u, err := ParseReference(text)
+		if err != nil {
+			t.Errorf("%q: Expect success but %v", text, err)
+			continue
+		}
+		if u.String() != text {
+			t.Errorf("%q: Expect round trip but %s", text, u)
+		}
+	}
+	u, _ := ParseReference("http:x")
+	if !u.IsAbsolute() || u.Scheme.Value != "http" {
+		t.Errorf("Expect http:x to be absolute but %+v", u)
+	}
+	u, _ = ParseReference("//h:/p?")
+	if !u.Authority.Port.Defined || u.Authority.Port.Raw != "" || !u.Query.Defined || u.Fragment.Defined {
+		t.Errorf("Expect empty port and query but %+v", u)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, c := range []struct {
+		text   string
+		offset int
+	}{
+		{"1http://a", 0},
+		{"http://a%zz/", 8},
+		{"http://[::1/", 7},
+		{"http://[1::2::3]/", 8},
+		{"http://h:8a/", 10},
+		{"http://a b", 8},
+		{"http:/a\x00", 7},
+	} {
+		_, err := Parse(c.text)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: Expect *ParseError but %v", c.text, err)
+			continue
+		}
+		if e.Offset != c.offset {
+			t.Errorf("%q: Expect error at %d but %v", c.text, c.offset, e)
+		}
+	}
+	if _, err := ParseReference("a:b:c/d"); err != nil {
+		t.Errorf("Expect a:b:c/d to be a URI but %v", err)
+	}
+	if _, err := ParseReference("a b"); err == nil {
+		t.Errorf("Expect error for a b")
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	// URI 可以嵌入到更大的文法中，例如尖括号包围的链接
+	link := goP2.Between(goP2.Chr('<'), goP2.Chr('>'), URIP)
+	state := goP2.BasicStateFromText("<https://example.com/a?b> rest")
+	x, err := link.Parse(&state)
+	if err != nil {
+		t.Fatalf("Expect success but %v", err)
+	}
+	if u := x.(*URI); u.Authority.Host.Value != "example.com" || u.Query.Raw != "b" {
+		t.Fatalf("Expect host example.com and query b but %+v", u)
+	}
+	if d, err := PercentDecode("a%2Fb%zz"); err == nil {
+		t.Fatalf("Expect error but %q", d)
+	}
+	if d, _ := PercentDecode("a%2Fb%C3%A9"); d != "a/bé" {
+		t.Fatalf("Expect a/bé but %q", d)
+	}
+}
@@ -0,0 +1,110 @@
+package goP2
+
+// UserState 在任意的 State 之上携带一个用户值，例如符号表、嵌套深度或者配置。
+// 用户值在 Begin 时保存快照，在 Rollback 时恢复，所以 Try 回溯之后用户值也会回到之前的状态。
+//
+// 快照只保存值本身，用户值应当当作不可变值使用：修改时构造新值并用 PutUserState 或者
+// ModifyUserState 写回，而不是原地修改 map 或者切片。
+type UserState struct {
+	State
+	value     interface{}
+	snapshots []userSnapshot
+}
+
+type userSnapshot struct {
+	tran  int
+	value interface{}
+}
+
+// NewUserState 构造一个以 initial 为初始用户值的 UserState
+func NewUserState(state State, initial interface{}) *UserState {
+	return &UserState{State: state, value: initial}
+}
+
+// User 返回当前的用户值
+func (s *UserState) User() interface{} {
+	return s.value
+}
+
+// Unwrap 返回被包装的 State
+func (s *UserState) Unwrap() State {
+	return s.State
+}
+
+// Begin 开始一个事务并保存用户值的快照
+func (s *UserState) Begin() int {
+	tran := s.State.Begin()
+	s.snapshots = append(s.snapshots, userSnapshot{tran, s.value})
+	return tran
+}
+
+// pop 弹出 tran 对应的快照。事务总是嵌套的，同一位置上可能有多个事务，所以从栈顶开始查找
+func (s *UserState) pop(tran int) (userSnapshot, bool) {
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i].tran == tran {
+			snap := s.snapshots[i]
+			s.snapshots = s.snapshots[:i]
+			return snap, true
+		}
+	}
+	return userSnapshot{}, false
+}
+
+// Commit 提交一个事务，丢弃对应的快照
+func (s *UserState) Commit(tran int) {
+	s.pop(tran)
+	s.State.Commit(tran)
+}
+
+// Rollback 取消一个事务，恢复对应的用户值快照
+func (s *UserState) Rollback(tran int) {
+	if snap, ok := s.pop(tran); ok {
+		s.value = snap.value
+	}
+	s.State.Rollback(tran)
+}
+
+// findUserState 沿着 Unwrap 链查找 UserState ，使得 Sized 、 IndentState 之类的包装不影响用户状态
+func findUserState(state State) (*UserState, error) {
+	s, ok := findState(state, func(s State) bool {
+		_, ok := s.(*UserState)
+		return ok
+	})
+	if !ok {
+		return nil, state.Trap("user state combinators require a UserState but %T", state)
+	}
+	return s.(*UserState), nil
+}
+
+// GetUserState 返回当前的用户值，不消费输入
+func GetUserState(state State) (interface{}, error) {
+	u, err := findUserState(state)
+	if err != nil {
+		return nil, err
+	}
+	return u.value, nil
+}
+
+// PutUserState 返回将用户值替换为 value 的算子，不消费输入，结果为 value
+func PutUserState(value interface{}) P {
+	return func(state State) (interface{}, error) {
+		u, err := findUserState(state)
+		if err != nil {
+			return nil, err
+		}
+		u.value = value
+		return value, nil
+	}
+}
+
+// ModifyUserState 返回用 fn 更新用户值的算子，不消费输入，结果为更新后的值
+func ModifyUserState(fn func(interface{}) interface{}) P {
+	return func(state State) (interface{}, error) {
+		u, err := findUserState(state)
+		if err != nil {
+			return nil, err
+		}
+		u.value = fn(u.value)
+		return u.value, nil
+	}
+}
@@ -0,0 +1,66 @@
+package goP2
+
+import (
+	"reflect"
+	"testing"
+)
+
+// declare 解析 "let x" 形式的声明，并把名字加入用户状态中的符号表
+var declare = Str("let ").Then(Many1(RuneOf("abcxyz")).Bind(ReturnString)).Bind(func(x interface{}) P {
+	return ModifyUserState(func(u interface{}) interface{} {
+		return append(append([]string{}, u.([]string)...), x.(string))
+	})
+})
+
+func TestUserStateRollback(t *testing.T) {
+	base := BasicStateFromText("let a;let b!")
+	state := NewUserState(&base, []string{})
+	item := declare.Over(Chr(';'))
+	if _, err := Many(item).Parse(state); err != nil {
+		t.Fatal(err)
+	}
+	// 第二个声明之后不是分号， Try 回溯时撤销了对符号表的修改
+	if got := state.User(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("Expect [a] but %v", got)
+	}
+	if state.Pos() != 6 {
+		t.Fatalf("Expect position 6 but %d", state.Pos())
+	}
+	re, err := P(GetUserState).Parse(state)
+	if err != nil || !reflect.DeepEqual(re, []string{"a"}) {
+		t.Fatalf("Expect [a] but %v, %v", re, err)
+	}
+}
+
+func TestUserStateNested(t *testing.T) {
+	base := BasicStateFromText("xy")
+	state := NewUserState(&base, 0)
+	inc := ModifyUserState(func(u interface{}) interface{} { return u.(int) + 1 })
+	// 同一位置上嵌套的事务：内层回溯，外层提交
+	p := Try(inc.Then(Choice(Try(inc.Then(Chr('z'))), Return(nil))))
+	if _, err := p.Parse(state); err != nil {
+		t.Fatal(err)
+	}
+	if state.User() != 1 {
+		t.Fatalf("Expect 1 but %v", state.User())
+	}
+	if _, err := Ahead(PutUserState(10).Then(Chr('x'))).Parse(state); err != nil {
+		t.Fatal(err)
+	}
+	if state.User() != 1 || state.Pos() != 0 {
+		t.Fatalf("Expect Ahead to restore user state 1 at 0 but %v at %d", state.User(), state.Pos())
+	}
+}
+
+func TestUserStateWrapped(t *testing.T) {
+	base := BasicStateFromText("ab\n  c")
+	state := NewIndentState(NewUserState(&base, "init"))
+	re, err := Sized(2, PutUserState("sized").Then(Many(One))).Then(P(GetUserState)).Parse(state)
+	if err != nil || re != "sized" {
+		t.Fatalf("Expect sized but %v, %v", re, err)
+	}
+	plain := BasicStateFromText("a")
+	if _, err := GetUserState(&plain); err == nil {
+		t.Fatalf("Expect error for a state without user state")
+	}
+}